@@ -0,0 +1,209 @@
+// Package batch runs the solver over many puzzles at once, for regression
+// checks over a puzzle corpus.
+package batch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// Item is one puzzle of a batch. Items that could not be read carry the
+// error instead of a state and are reported without being solved.
+type Item struct {
+	Name  string
+	State *solver.FieldState
+	// ExpectedHash, when set, is compared with the hash of the solution.
+	ExpectedHash string
+	Err          error
+}
+
+// Options controls a batch run. Zero values pick the defaults.
+type Options struct {
+	// Workers is the number of puzzles solved at the same time.
+	Workers int
+	// Timeout bounds the time spent on one puzzle.
+	Timeout time.Duration
+	Solve   solver.SolveOptions
+	// Cancel, when closed, stops the run. The puzzle being solved is
+	// reported as a timeout and the ones not started as cancelled.
+	Cancel <-chan struct{}
+	// Progress, when set, is called after each puzzle.
+	Progress func(done, total int)
+}
+
+const defaultTimeout = 30 * time.Second
+
+// Statuses of a Result.
+const (
+	StatusSolved   = "solved"
+	StatusUnsolved = "unsolved"
+	StatusTimeout  = "timeout"
+	StatusMismatch = "mismatch"
+	StatusError    = "error"
+	StatusCancel   = "cancelled"
+)
+
+// Result is the outcome for one puzzle.
+type Result struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Hash     string  `json:"hash,omitempty"`
+	Seconds  float64 `json:"seconds"`
+	Error    string  `json:"error,omitempty"`
+	Expected string  `json:"expected_hash,omitempty"`
+}
+
+// Report sums up a batch run. Results are in the order of the items.
+type Report struct {
+	Total    int      `json:"total"`
+	Solved   int      `json:"solved"`
+	Unsolved int      `json:"unsolved"`
+	Timeouts int      `json:"timeouts"`
+	Mismatch int      `json:"mismatches"`
+	Errors   int      `json:"errors"`
+	Cancel   int      `json:"cancelled,omitempty"`
+	Seconds  float64  `json:"seconds"`
+	Results  []Result `json:"results"`
+}
+
+// Run solves every item on a pool of workers.
+func Run(items []Item, options Options) *Report {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	start := time.Now()
+	report := &Report{Total: len(items), Results: make([]Result, len(items))}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				report.Results[i] = solve(items[i], timeout, options)
+				mu.Lock()
+				done++
+				if options.Progress != nil {
+					options.Progress(done, len(items))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	next := 0
+feed:
+	for ; next < len(items); next++ {
+		select {
+		case jobs <- next:
+		case <-options.Cancel:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	for i := next; i < len(items); i++ {
+		report.Results[i] = Result{Name: items[i].Name, Status: StatusCancel, Expected: items[i].ExpectedHash}
+	}
+
+	for _, result := range report.Results {
+		switch result.Status {
+		case StatusSolved:
+			report.Solved++
+		case StatusUnsolved:
+			report.Unsolved++
+		case StatusTimeout:
+			report.Timeouts++
+		case StatusMismatch:
+			report.Mismatch++
+		case StatusCancel:
+			report.Cancel++
+		default:
+			report.Errors++
+		}
+	}
+	report.Seconds = time.Since(start).Seconds()
+	return report
+}
+
+func solve(item Item, timeout time.Duration, options Options) (result Result) {
+	result = Result{Name: item.Name, Expected: item.ExpectedHash}
+	defer func() {
+		// A solver bug on one puzzle is reported rather than ending the run.
+		if r := recover(); r != nil {
+			result.Status, result.Error = StatusError, fmt.Sprint(r)
+		}
+	}()
+	if item.Err != nil {
+		result.Status, result.Error = StatusError, item.Err.Error()
+		return result
+	}
+
+	copied, err := solver.FromListToState(item.State.ToList())
+	if err != nil {
+		result.Status, result.Error = StatusError, err.Error()
+		return result
+	}
+	state := copied["state"].(*solver.FieldState)
+	cancel := make(chan struct{})
+	var stop sync.Once
+	timer := time.AfterFunc(timeout, func() { stop.Do(func() { close(cancel) }) })
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-options.Cancel:
+			stop.Do(func() { close(cancel) })
+		case <-finished:
+		}
+	}()
+	solveOptions := options.Solve
+	solveOptions.Cancel = cancel
+	ps := solver.NewPuzzleSolver(state)
+	ps.SetOptions(solveOptions)
+
+	start := time.Now()
+	_, err = ps.Solve()
+	result.Seconds = time.Since(start).Seconds()
+	timer.Stop()
+
+	switch {
+	case err == solver.ErrCancelled:
+		result.Status = StatusTimeout
+	case err != nil:
+		result.Status, result.Error = StatusUnsolved, err.Error()
+	default:
+		result.Hash = Hash(state.ToList())
+		result.Status = StatusSolved
+		if item.ExpectedHash != "" && item.ExpectedHash != result.Hash {
+			result.Status = StatusMismatch
+		}
+	}
+	return result
+}
+
+// Hash is a stable fingerprint of a solved grid, so reports from two runs
+// can be compared without storing the grids.
+func Hash(grid [][]int) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d", len(grid))
+	for _, row := range grid {
+		for _, value := range row {
+			fmt.Fprintf(h, ",%d", value)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
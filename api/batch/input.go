@@ -0,0 +1,132 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// line is one record of a JSONL corpus. The puzzle is either a grid with
+// zeros for blanks or the text of a puzzle in any supported format, such
+// as a puzz.link URL.
+type line struct {
+	Name         string  `json:"name"`
+	Grid         [][]int `json:"grid"`
+	Puzzle       string  `json:"puzzle"`
+	ExpectedHash string  `json:"expected_hash"`
+}
+
+// maxLine bounds a line of a JSONL corpus, well above a grid of
+// formats.MaxSize a side.
+const maxLine = 1024 * 1024
+
+// ReadJSONL reads a corpus with one JSON object per line. Blank lines are
+// skipped; lines that cannot be read become items carrying the error.
+func ReadJSONL(r io.Reader) ([]Item, error) {
+	var items []Item
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var l line
+		item := Item{Name: fmt.Sprintf("line %d", number)}
+		if err := json.Unmarshal([]byte(text), &l); err != nil {
+			item.Err = err
+			items = append(items, item)
+			continue
+		}
+		if l.Name != "" {
+			item.Name = l.Name
+		}
+		item.ExpectedHash = l.ExpectedHash
+		item.State, item.Err = l.state()
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+func (l line) state() (*solver.FieldState, error) {
+	switch {
+	case l.Grid != nil:
+		if err := checkGrid(l.Grid); err != nil {
+			return nil, err
+		}
+		result, err := solver.FromListToState(l.Grid)
+		if err != nil {
+			return nil, err
+		}
+		return result["state"].(*solver.FieldState), nil
+	case l.Puzzle != "":
+		data := []byte(l.Puzzle)
+		puzzle, err := formats.Parse(formats.DetectFormat(data), data)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkGrid(puzzle.State.ToList()); err != nil {
+			return nil, err
+		}
+		return puzzle.State, nil
+	}
+	return nil, errors.New("record has neither grid nor puzzle")
+}
+
+// checkGrid holds a grid to the boards the readers accept: square, with a
+// side between 2 and formats.MaxSize.
+func checkGrid(grid [][]int) error {
+	if len(grid) < 2 || len(grid) > formats.MaxSize {
+		return fmt.Errorf("board side must be between 2 and %d", formats.MaxSize)
+	}
+	for x, row := range grid {
+		if len(row) != len(grid) {
+			return fmt.Errorf("row %d has %d cells, expected %d", x, len(row), len(grid))
+		}
+	}
+	return nil
+}
+
+// Load reads a corpus from a directory of puzzle files in any supported
+// format, or from a JSONL file. Solutions carried by the files become the
+// expected hashes.
+func Load(path string) ([]Item, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return ReadJSONL(file)
+	}
+
+	puzzles, errs, err := formats.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var items []Item
+	for _, puzzle := range puzzles {
+		item := Item{Name: puzzle.Metadata["source"], State: puzzle.State}
+		if puzzle.Solution != nil {
+			item.ExpectedHash = Hash(puzzle.Solution.ToList())
+		}
+		items = append(items, item)
+	}
+	for _, err := range errs {
+		items = append(items, Item{Name: "unreadable file", Err: err})
+	}
+	return items, nil
+}
@@ -4,18 +4,32 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
 
 	_ "github.com/jinzhu/gorm/dialects/postgres" //postgres database driver
 	_ "github.com/jinzhu/gorm/dialects/sqlite"   // sqlite database driver
+	"github.com/alcoccoque/puzzle-solver-go/api/daily"
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
+	"github.com/alcoccoque/puzzle-solver-go/api/live"
 	"github.com/alcoccoque/puzzle-solver-go/api/models"
+	"github.com/alcoccoque/puzzle-solver-go/api/pool"
+	"github.com/alcoccoque/puzzle-solver-go/api/websocket"
 )
 
 type Server struct {
 	DB     *gorm.DB
 	Router *mux.Router
+	Jobs   *jobs.Queue
+	Pool   *pool.Pool
+	Daily  *daily.Scheduler
+
+	LiveSolves live.Slots
+	WebSocket  websocket.Options
 }
 
 func (server *Server) Initialize(Dbdriver, DbUser, DbPassword, DbPort, DbHost, DbName string) {
@@ -44,14 +58,92 @@ func (server *Server) Initialize(Dbdriver, DbUser, DbPassword, DbPort, DbHost, D
 		server.DB.Exec("PRAGMA foreign_keys = ON")
 	}
 
-	server.DB.Debug().AutoMigrate(&models.User{}, &models.Post{}) //database migration
+	server.DB.Debug().AutoMigrate(&models.User{}, &models.Job{}, &models.PoolPuzzle{}, &models.DailyPuzzle{}) //database migration
+	err = models.Migrate(server.DB)
+	if err != nil {
+		log.Fatalf("Cannot migrate the matrices table: %v", err)
+	}
+
+	err = models.FailUnfinishedJobs(server.DB)
+	if err != nil {
+		log.Printf("Cannot mark unfinished jobs as failed: %v", err)
+	}
+	server.Jobs = jobs.NewQueue(jobs.Options{Store: models.JobStore{DB: server.DB}})
+	server.Pool = pool.New(poolOptions(models.PoolStore{DB: server.DB}))
+	server.Daily = daily.NewScheduler(dailyOptions(models.DailyStore{DB: server.DB}, server.Pool))
+	server.LiveSolves, server.WebSocket = liveSettings()
 
 	server.Router = mux.NewRouter()
 
 	server.initializeRoutes()
 }
 
+// poolOptions reads the puzzle pool settings from the environment:
+// POOL_SIZES, a comma-separated list of board sizes stocked in every
+// difficulty, and POOL_LEVEL, the number of puzzles kept per size and
+// difficulty.
+func poolOptions(store pool.Store) pool.Options {
+	options := pool.Options{Store: store}
+	if sizes := os.Getenv("POOL_SIZES"); sizes != "" {
+		var stocked []int
+		for _, field := range strings.Split(sizes, ",") {
+			size, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || size < 2 {
+				log.Printf("Ignoring pool size %q", field)
+				continue
+			}
+			stocked = append(stocked, size)
+		}
+		options.Buckets = pool.Buckets(stocked)
+	}
+	if level := os.Getenv("POOL_LEVEL"); level != "" {
+		var err error
+		options.Level, err = strconv.Atoi(level)
+		if err != nil {
+			log.Printf("Ignoring pool level %q", level)
+		}
+	}
+	return options
+}
+
+// dailyOptions reads the puzzle of the day settings from the environment:
+// DAILY_SIZE and DAILY_DIFFICULTY, the kind of puzzle picked every day.
+func dailyOptions(store daily.Store, stock *pool.Pool) daily.Options {
+	options := daily.Options{Store: store, Pool: stock, Difficulty: os.Getenv("DAILY_DIFFICULTY")}
+	if size := os.Getenv("DAILY_SIZE"); size != "" {
+		var err error
+		options.Size, err = strconv.Atoi(size)
+		if err != nil {
+			log.Printf("Ignoring daily size %q", size)
+		}
+	}
+	return options
+}
+
+// liveSettings reads the live solve settings from the environment:
+// LIVE_SOLVES, the number of live solves run at once, and LIVE_ORIGINS, a
+// comma-separated list of the page origins allowed to open one.
+func liveSettings() (live.Slots, websocket.Options) {
+	slots := 0
+	if solves := os.Getenv("LIVE_SOLVES"); solves != "" {
+		var err error
+		slots, err = strconv.Atoi(solves)
+		if err != nil {
+			log.Printf("Ignoring live solves %q", solves)
+		}
+	}
+	options := websocket.Options{}
+	for _, field := range strings.Split(os.Getenv("LIVE_ORIGINS"), ",") {
+		if origin := strings.TrimSpace(field); origin != "" {
+			options.Origins = append(options.Origins, origin)
+		}
+	}
+	return live.NewSlots(slots), options
+}
+
 func (server *Server) Run(addr string) {
+	server.Pool.Start()
+	server.Daily.Start()
 	fmt.Println("Listening to port 8080")
 	log.Fatal(http.ListenAndServe(addr, server.Router))
 }
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/auth"
+	"github.com/alcoccoque/puzzle-solver-go/api/batch"
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
+	"github.com/alcoccoque/puzzle-solver-go/api/responses"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// Limits on a batch request. A batch that could take longer than
+// maxBatchWait runs as a job rather than inside the request.
+const (
+	maxBatchPuzzles = 1000
+	maxBatchTimeout = 60 * time.Second
+	maxBatchWait    = 30 * time.Second
+	maxBatchBytes   = 8 << 20
+)
+
+// SolveBatch solves a JSONL corpus sent as the request body, one puzzle per
+// line as {"name": ..., "grid": [[...]]} or {"name": ..., "puzzle": "..."},
+// and returns the batch report. "?timeout=" is the per-puzzle limit in
+// seconds, "?workers=" the number of puzzles solved at once, at most one
+// per CPU, and "?mode=" the search mode.
+//
+// A batch is answered with its report when its puzzles cannot take longer
+// than maxBatchWait at the given timeout. Larger ones are queued, and the
+// job to poll carries the report once done. A queued batch stopped by the
+// job budget or by its owner still carries the report, with the puzzles it
+// did not get to marked as cancelled.
+func (server *Server) SolveBatch(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	options := batch.Options{Timeout: 10 * time.Second}
+	query := r.URL.Query()
+	if timeout := query.Get("timeout"); timeout != "" {
+		seconds, err := strconv.ParseFloat(timeout, 64)
+		options.Timeout = time.Duration(seconds * float64(time.Second))
+		if err != nil || options.Timeout <= 0 || options.Timeout > maxBatchTimeout {
+			responses.ERROR(w, http.StatusBadRequest, errors.New("timeout must be between 0 and 60 seconds"))
+			return
+		}
+	}
+	if workers := query.Get("workers"); workers != "" {
+		options.Workers, err = strconv.Atoi(workers)
+		if err != nil || options.Workers < 1 {
+			responses.ERROR(w, http.StatusBadRequest, errors.New("workers must be a positive number"))
+			return
+		}
+	}
+	if options.Workers < 1 || options.Workers > runtime.NumCPU() {
+		options.Workers = runtime.NumCPU()
+	}
+	switch query.Get("mode") {
+	case "", "cells":
+	case "regions":
+		options.Solve.Mode = solver.ModeRegions
+	case "exactcover":
+		options.Solve.Mode = solver.ModeExactCover
+	default:
+		responses.ERROR(w, http.StatusBadRequest, errors.New("mode must be cells, regions or exactcover"))
+		return
+	}
+
+	items, err := batch.ReadJSONL(http.MaxBytesReader(w, r.Body, maxBatchBytes))
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if len(items) == 0 || len(items) > maxBatchPuzzles {
+		responses.ERROR(w, http.StatusUnprocessableEntity, errors.New("a batch holds between 1 and 1000 puzzles"))
+		return
+	}
+	rounds := (len(items) + options.Workers - 1) / options.Workers
+	if time.Duration(rounds)*options.Timeout <= maxBatchWait {
+		responses.JSON(w, http.StatusOK, batch.Run(items, options))
+		return
+	}
+
+	job, err := server.Jobs.Submit("batch", uid, func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+		options.Cancel = cancel
+		options.Progress = func(done, total int) {
+			report(jobs.Progress{Fraction: float64(done) / float64(total)})
+		}
+		result := batch.Run(items, options)
+		select {
+		case <-cancel:
+			return jobs.Output{Report: result}, solver.ErrCancelled
+		default:
+		}
+		return jobs.Output{Report: result}, nil
+	})
+	if err == jobs.ErrQueueFull {
+		responses.ERROR(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/jobs/%s", r.Host, job.ID))
+	responses.JSON(w, http.StatusAccepted, job)
+}
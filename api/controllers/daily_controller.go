@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/daily"
+	"github.com/alcoccoque/puzzle-solver-go/api/responses"
+	"github.com/gorilla/mux"
+)
+
+// GetDaily returns the puzzle of the day, the same for every player.
+func (server *Server) GetDaily(w http.ResponseWriter, r *http.Request) {
+	server.writeDaily(w, server.Daily.Today())
+}
+
+// GetDailyByDate returns the puzzle of a past date, or of today.
+func (server *Server) GetDailyByDate(w http.ResponseWriter, r *http.Request) {
+	date := mux.Vars(r)["date"]
+	if _, err := time.Parse(daily.DateLayout, date); err != nil {
+		responses.ERROR(w, http.StatusBadRequest, errors.New("date must be written as YYYY-MM-DD"))
+		return
+	}
+	server.writeDaily(w, date)
+}
+
+func (server *Server) writeDaily(w http.ResponseWriter, date string) {
+	puzzle, err := server.Daily.Get(date)
+	switch {
+	case err == daily.ErrNotFound:
+		responses.ERROR(w, http.StatusNotFound, errors.New("Daily Puzzle Not Found"))
+	case err == daily.ErrNotYet:
+		responses.ERROR(w, http.StatusNotFound, err)
+	case err == daily.ErrNotReady:
+		responses.ERROR(w, http.StatusServiceUnavailable, err)
+	case err != nil:
+		responses.ERROR(w, http.StatusInternalServerError, err)
+	default:
+		responses.JSON(w, http.StatusOK, puzzle)
+	}
+}
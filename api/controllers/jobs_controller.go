@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/auth"
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
+	"github.com/alcoccoque/puzzle-solver-go/api/models"
+	"github.com/alcoccoque/puzzle-solver-go/api/responses"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"github.com/gorilla/mux"
+)
+
+// callerJob returns the job named in the URL if it belongs to the caller,
+// and otherwise answers the request and returns false.
+func (server *Server) callerJob(w http.ResponseWriter, r *http.Request) (jobs.Job, bool) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return jobs.Job{}, false
+	}
+	job, err := server.Jobs.Get(mux.Vars(r)["id"])
+	if err == jobs.ErrNotFound {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Job Not Found"))
+		return jobs.Job{}, false
+	}
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return jobs.Job{}, false
+	}
+	if job.UserID != uid {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+		return jobs.Job{}, false
+	}
+	return job, true
+}
+
+// GetJob reports the status and progress of a background job of the caller
+// and, once it succeeded, its result.
+func (server *Server) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := server.callerJob(w, r)
+	if !ok {
+		return
+	}
+	responses.JSON(w, http.StatusOK, job)
+}
+
+// SolveJob queues the solving of a puzzle, given as {"grid": [[...]]} or
+// {"matrix_id": 1}, and answers with the job to poll.
+func (server *Server) SolveJob(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	request := struct {
+		Grid     [][]int `json:"grid"`
+		MatrixID uint64  `json:"matrix_id"`
+	}{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	grid := request.Grid
+	if request.MatrixID != 0 {
+		matrix := models.Matrix{}
+		matrixReceived, err := matrix.FindMatrixByID(server.DB, request.MatrixID)
+		if err != nil {
+			responses.ERROR(w, http.StatusNotFound, errors.New("Matrix Not Found"))
+			return
+		}
+		grid, err = matrixReceived.Grid()
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	err = checkRows(grid)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	state := result["state"].(*solver.FieldState)
+
+	job, err := server.Jobs.Submit("solve", uid, func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+		ps := solver.NewPuzzleSolver(state)
+		ps.SetOptions(solver.SolveOptions{
+			Cancel: cancel,
+			Progress: func(nodes, depth int) {
+				report(jobs.Progress{Nodes: nodes, Depth: depth})
+			},
+		})
+		solved, err := ps.Solve()
+		if err != nil {
+			return jobs.Output{}, err
+		}
+		return jobs.Output{MatrixID: request.MatrixID, Solution: solved["solved_puzzle"].([][]int)}, nil
+	})
+	if err == jobs.ErrQueueFull {
+		responses.ERROR(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/jobs/%s", r.Host, job.ID))
+	responses.JSON(w, http.StatusAccepted, job)
+}
+
+// CancelJob stops a queued or running job of the caller.
+func (server *Server) CancelJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := server.callerJob(w, r)
+	if !ok {
+		return
+	}
+	err := server.Jobs.Cancel(job.ID)
+	if err == jobs.ErrFinished {
+		responses.ERROR(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+	job, _ = server.Jobs.Get(job.ID)
+	responses.JSON(w, http.StatusAccepted, job)
+}
+
+// jobEventInterval is how often JobEvents samples a job.
+const jobEventInterval = 250 * time.Millisecond
+
+// JobEvents streams a job of the caller as Server-Sent Events: a
+// "progress" event with the job whenever it changed, and a "done" event
+// once it finished. EventSource cannot set headers, so the token may come
+// as "?token=".
+func (server *Server) JobEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		responses.ERROR(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	job, ok := server.callerJob(w, r)
+	if !ok {
+		return
+	}
+	id := job.ID
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(jobEventInterval)
+	defer ticker.Stop()
+	var last []byte
+	for {
+		data, err := json.Marshal(job)
+		if err != nil {
+			return
+		}
+		event := "progress"
+		if job.Status.Finished() {
+			event = "done"
+		}
+		if !bytes.Equal(data, last) {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+			flusher.Flush()
+			last = data
+		}
+		if job.Status.Finished() {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		job, err = server.Jobs.Get(id)
+		if err != nil {
+			return
+		}
+	}
+}
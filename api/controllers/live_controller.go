@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/live"
+	"github.com/alcoccoque/puzzle-solver-go/api/models"
+	"github.com/alcoccoque/puzzle-solver-go/api/responses"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"github.com/alcoccoque/puzzle-solver-go/api/websocket"
+)
+
+// LiveSolve upgrades to a WebSocket and solves a puzzle while streaming
+// every move of the search, see package live. The puzzle is a stored
+// matrix, "?matrix_id=", or a puzz.link URL, "?puzzle="; "?rate=" is the
+// number of moves sent per second and "?mode=" the search mode. The client
+// stops the solve by sending "stop" or by closing the connection.
+//
+// Browsers cannot set headers on a WebSocket, so the token may come as
+// "?token=". Only the origins in server.WebSocket may connect, and a
+// request beyond the live solves server.LiveSolves allows at once is
+// answered 503.
+func (server *Server) LiveSolve(w http.ResponseWriter, r *http.Request) {
+	options := live.Options{}
+	query := r.URL.Query()
+	if rate := query.Get("rate"); rate != "" {
+		var err error
+		options.Rate, err = strconv.Atoi(rate)
+		if err != nil || options.Rate < 1 || options.Rate > live.MaxRate {
+			responses.ERROR(w, http.StatusBadRequest, errors.New("rate must be between 1 and 1000"))
+			return
+		}
+	}
+	switch query.Get("mode") {
+	case "", "cells":
+	case "regions":
+		options.Solve.Mode = solver.ModeRegions
+	default:
+		responses.ERROR(w, http.StatusBadRequest, errors.New("mode must be cells or regions"))
+		return
+	}
+
+	var state *solver.FieldState
+	switch {
+	case query.Get("matrix_id") != "":
+		mid, err := strconv.ParseUint(query.Get("matrix_id"), 10, 64)
+		if err != nil {
+			responses.ERROR(w, http.StatusBadRequest, err)
+			return
+		}
+		matrix := models.Matrix{}
+		matrixReceived, err := matrix.FindMatrixByID(server.DB, mid)
+		if err != nil {
+			responses.ERROR(w, http.StatusNotFound, errors.New("Matrix Not Found"))
+			return
+		}
+		grid, err := matrixReceived.Grid()
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+		result, err := solver.FromListToState(grid)
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+		state = result["state"].(*solver.FieldState)
+	case query.Get("puzzle") != "":
+		var err error
+		state, err = formats.DecodePuzzLink(query.Get("puzzle"))
+		if err != nil {
+			responses.ERROR(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+	default:
+		responses.ERROR(w, http.StatusBadRequest, errors.New("matrix_id or puzzle is required"))
+		return
+	}
+
+	if !server.LiveSolves.Take() {
+		responses.ERROR(w, http.StatusServiceUnavailable, errors.New("too many live solves, try again later"))
+		return
+	}
+	defer server.LiveSolves.Release()
+	conn, err := websocket.Upgrade(w, r, server.WebSocket)
+	if err != nil {
+		return
+	}
+	defer conn.Close(websocket.CloseNormal)
+	if err := live.Run(conn, state, options); err != nil {
+		log.Printf("live solve: %v", err)
+	}
+}
@@ -1,102 +1,796 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image/png"
+	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/alcoccoque/puzzle-solver-go/api/models"
 	"github.com/alcoccoque/puzzle-solver-go/api/responses"
 	"github.com/alcoccoque/puzzle-solver-go/api/auth"
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
+	"github.com/alcoccoque/puzzle-solver-go/api/pool"
+	"github.com/alcoccoque/puzzle-solver-go/api/render"
 	"github.com/alcoccoque/puzzle-solver-go/api/utils/formaterror"
 	"github.com/alcoccoque/puzzle-solver-go/api/solver"
 )
 
+// solveRequest is the body of POST /matrices/solve: the rows of a square
+// board, 0 for the empty cells.
+type solveRequest struct {
+	Rows [][]int `json:"rows"`
+}
+
+// solvedMatrix is the answer of SolveMatrix: the stored puzzle and a
+// solution, which the matrix only keeps when it is the only one.
+type solvedMatrix struct {
+	Matrix   *models.Matrix `json:"matrix"`
+	Solution [][]int        `json:"solution"`
+}
+
+// solveTimeout bounds the searches SolveMatrix runs inside the request.
+// Boards that take longer can be solved as a job through /solve.
+const solveTimeout = 10 * time.Second
+
+// errSolveTimeout answers SolveMatrix requests that ran out of time.
+var errSolveTimeout = fmt.Errorf("puzzle took longer than %v to solve, queue it at /solve instead", solveTimeout)
+
 func (server *Server) SolveMatrix(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		responses.ERROR(w, http.StatusUnprocessableEntity, err)
 		return
 	}
-	var solveMatrixSchema solver.SolveMatrix
-	err = json.Unmarshal(body, &solveMatrixSchema)
+	request := solveRequest{}
+	err = json.Unmarshal(body, &request)
 	if err != nil {
 		responses.ERROR(w, http.StatusUnprocessableEntity, err)
 		return
 	}
+	err = checkRows(request.Rows)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
 
-	result, err := solver.FromListToState(solveMatrixSchema.Rows)
+	cancel := make(chan struct{})
+	timer := time.AfterFunc(solveTimeout, func() { close(cancel) })
+	defer timer.Stop()
+
+	matrix := models.Matrix{UserID: uid}
+	matrix.Prepare()
+	err = matrix.SetClues(request.Rows)
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	err = matrix.Rate(cancel)
+	if err == solver.ErrCancelled {
+		responses.ERROR(w, http.StatusServiceUnavailable, errSolveTimeout)
+		return
+	}
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	// A unique solution comes with the rating; otherwise any one will do.
+	solution := [][]int(matrix.Solution)
+	if solution == nil {
+		result, err := solver.FromListToState(request.Rows)
+		if err != nil {
+			responses.ERROR(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		ps := solver.NewPuzzleSolver(result["state"].(*solver.FieldState))
+		ps.SetOptions(solver.SolveOptions{Cancel: cancel})
+		solved, err := ps.Solve()
+		if err == solver.ErrCancelled {
+			responses.ERROR(w, http.StatusServiceUnavailable, errSolveTimeout)
+			return
+		}
+		if err != nil {
+			responses.ERROR(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		solution = solved["solved_puzzle"].([][]int)
+	}
+	err = matrix.Validate()
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
 		return
 	}
+	status := http.StatusCreated
+	matrixCreated, err := matrix.SaveMatrix(server.DB)
+	if err == models.ErrDuplicateMatrix {
+		status, err = http.StatusOK, nil
+	}
+	if err != nil {
+		formattedError := formaterror.FormatError(err.Error())
+		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/matrices/%d", r.Host, matrixCreated.ID))
+	responses.JSON(w, status, solvedMatrix{Matrix: matrixCreated, Solution: solution})
+}
+
+// checkRows rejects boards that are not square or have a side outside 2 to
+// formats.MaxSize, before they reach the solver.
+func checkRows(rows [][]int) error {
+	if len(rows) < 2 || len(rows) > formats.MaxSize {
+		return fmt.Errorf("board side must be between 2 and %d", formats.MaxSize)
+	}
+	for x, row := range rows {
+		if len(row) != len(rows) {
+			return fmt.Errorf("row %d has %d cells, expected %d", x, len(row), len(rows))
+		}
+	}
+	return nil
+}
+
+// templateAttempts bounds the solutions drawn for a template, and
+// ratedAttempts the ones drawn for a difficulty.
+const (
+	templateAttempts = 500
+	ratedAttempts    = 50
+)
 
-	state, ok := result["state"].(*models.FieldState)
-	if !ok {
-		responses.ERROR(w, http.StatusBadRequest, errors.New("Invalid state"))
+// generateRequest describes a puzzle to generate: either a template, a size
+// and a difficulty, or a size and a share of filled cells with an optional
+// clue symmetry.
+type generateRequest struct {
+	Template         [][]int
+	Size             int
+	Difficulty       string
+	FilledPercentage float64
+	Symmetry         solver.ClueSymmetry
+}
+
+// parseGenerateRequest reads "?size=" with "?difficulty=" or with
+// "?filled_percentage=" and "?symmetry=", or a JSON body
+// {"template": [[...]]} whose cells are 0 for no clue, -1 for a clue the
+// generator picks or a fixed clue value. Sizes are held to the same bounds
+// as the boards read by the importers.
+func parseGenerateRequest(r *http.Request) (generateRequest, error) {
+	request := generateRequest{}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return request, err
+	}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		template := struct {
+			Template [][]int `json:"template"`
+		}{}
+		err = json.Unmarshal(body, &template)
+		if err != nil {
+			return request, err
+		}
+		if len(template.Template) == 0 {
+			return request, errors.New("Required Template")
+		}
+		err = checkRows(template.Template)
+		if err != nil {
+			return request, err
+		}
+		request.Template = template.Template
+		request.Size = len(template.Template)
+		return request, nil
+	}
+
+	query := r.URL.Query()
+	request.Size, err = strconv.Atoi(query.Get("size"))
+	if err != nil || request.Size < 2 || request.Size > formats.MaxSize {
+		return request, fmt.Errorf("size must be between 2 and %d", formats.MaxSize)
+	}
+	if difficulty := query.Get("difficulty"); difficulty != "" {
+		for _, d := range solver.Difficulties {
+			if d == difficulty {
+				request.Difficulty = difficulty
+				return request, nil
+			}
+		}
+		return request, errors.New("difficulty must be one of " + strings.Join(solver.Difficulties, ", "))
+	}
+	request.FilledPercentage, err = strconv.ParseFloat(query.Get("filled_percentage"), 64)
+	if err != nil {
+		return request, err
+	}
+	request.Symmetry, err = clueSymmetry(query.Get("symmetry"))
+	return request, err
+}
+
+func (g generateRequest) generate(cancel <-chan struct{}, progress func(float64)) ([][]int, error) {
+	generator := solver.NewPuzzleGenerator(g.Size)
+	generator.SetCancel(cancel)
+	generator.SetProgress(func(done, total int) {
+		progress(float64(done) / float64(total))
+	})
+	if g.Template != nil {
+		return generator.GenerateFromTemplate(g.Template, templateAttempts)
+	}
+	if g.Difficulty != "" {
+		return generator.GenerateRated(g.Difficulty, ratedAttempts)
+	}
+	generator.SetSymmetry(g.Symmetry)
+	return generator.GeneratePuzzle(g.FilledPercentage)
+}
+
+// GenerateMatrix queues the generation of a puzzle, which is stored as a
+// matrix of the caller once done, and answers with the job to poll. A size
+// and difficulty in stock in the pool is answered right away with the
+// matrix instead.
+func (server *Server) GenerateMatrix(w http.ResponseWriter, r *http.Request) {
+	request, err := parseGenerateRequest(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
+	if request.Difficulty != "" {
+		puzzle, err := server.Pool.Take(pool.Bucket{Size: request.Size, Difficulty: request.Difficulty})
+		if err != nil && err != pool.ErrEmpty && err != pool.ErrUnknownBucket {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+		if err == nil {
+			matrix, err := pooledMatrix(uid, puzzle)
+			if err != nil {
+				responses.ERROR(w, http.StatusInternalServerError, err)
+				return
+			}
+			matrixCreated, err := matrix.SaveMatrix(server.DB)
+			status := http.StatusCreated
+			if err == models.ErrDuplicateMatrix {
+				status = http.StatusOK
+			} else if err != nil {
+				formattedError := formaterror.FormatError(err.Error())
+				responses.ERROR(w, http.StatusInternalServerError, formattedError)
+				return
+			}
+			w.Header().Set("Location", fmt.Sprintf("%s/matrices/%d", r.Host, matrixCreated.ID))
+			responses.JSON(w, status, matrixCreated)
+			return
+		}
+	}
 
-	solver := solver.NewPuzzleSolver(state)
-	solvedResult, err := solver.Solve()
+	job, err := server.Jobs.Submit("generate", uid, func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+		board, err := request.generate(cancel, func(done float64) {
+			report(jobs.Progress{Fraction: done})
+		})
+		if err != nil {
+			return jobs.Output{}, err
+		}
+		matrix, err := generatedMatrix(uid, board, cancel)
+		if err != nil {
+			return jobs.Output{}, err
+		}
+		// A puzzle generated before is as good a result as a new one.
+		matrixCreated, err := matrix.SaveMatrix(server.DB)
+		if err != nil && err != models.ErrDuplicateMatrix {
+			return jobs.Output{}, err
+		}
+		return jobs.Output{MatrixID: matrixCreated.ID}, nil
+	})
+	if err == jobs.ErrQueueFull {
+		responses.ERROR(w, http.StatusServiceUnavailable, err)
+		return
+	}
 	if err != nil {
 		responses.ERROR(w, http.StatusInternalServerError, err)
 		return
 	}
+	w.Header().Set("Location", fmt.Sprintf("%s/jobs/%s", r.Host, job.ID))
+	responses.JSON(w, http.StatusAccepted, job)
+}
+
+// generatedMatrix makes a matrix of a generated board, with its solution
+// and difficulty. Closing cancel stops the rating.
+func generatedMatrix(uid uint32, board [][]int, cancel <-chan struct{}) (*models.Matrix, error) {
+	matrix := models.Matrix{UserID: uid, Source: "generator"}
+	matrix.Prepare()
+	err := matrix.SetClues(board)
+	if err != nil {
+		return nil, err
+	}
+	err = matrix.Rate(cancel)
+	if err != nil {
+		return nil, err
+	}
+	return &matrix, nil
+}
 
-	solvedPuzzle, ok := solvedResult["solved_puzzle"].([][]int)
-	if !ok {
-		responses.ERROR(w, http.StatusInternalServerError, errors.New("Failed to solve puzzle"))
+// pooledMatrix makes a matrix of a puzzle taken from the pool, with the
+// solution and difficulty stocked along with it. Puzzles stocked without
+// a solution are rated instead.
+func pooledMatrix(uid uint32, puzzle pool.Puzzle) (*models.Matrix, error) {
+	if puzzle.Solution == nil {
+		return generatedMatrix(uid, puzzle.Clues, nil)
+	}
+	matrix := models.Matrix{UserID: uid, Source: "generator"}
+	matrix.Prepare()
+	err := matrix.SetClues(puzzle.Clues)
+	if err != nil {
+		return nil, err
+	}
+	err = matrix.SetSolution(puzzle.Solution)
+	if err != nil {
+		return nil, err
+	}
+	matrix.Difficulty = puzzle.Difficulty
+	return &matrix, nil
+}
+
+// ImportPuzzLink stores the puzzle of a puzz.link URL given as
+// {"url": "..."}. A puzzle the caller stored before is answered with its
+// stored matrix and 200 instead of 201.
+func (server *Server) ImportPuzzLink(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	link := struct {
+		URL string `json:"url"`
+	}{}
+	err = json.Unmarshal(body, &link)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	state, err := formats.DecodePuzzLink(link.URL)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
 
-	newMatrix := models.CreateMatrix{
-		Coordinates: solvedPuzzle,
-		UserID:      auth.ExtractTokenID(r),
+	matrix := models.Matrix{UserID: uid}
+	matrix.Prepare()
+	err = matrix.SetClues(state.ToList())
+	if err == nil {
+		err = matrix.Validate()
+	}
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
 	}
+	status := http.StatusCreated
+	matrixCreated, err := matrix.SaveMatrix(server.DB)
+	if err == models.ErrDuplicateMatrix {
+		status, err = http.StatusOK, nil
+	}
+	if err != nil {
+		formattedError := formaterror.FormatError(err.Error())
+		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/matrices/%d", r.Host, matrixCreated.ID))
+	responses.JSON(w, status, matrixCreated)
+}
 
-	matrix, err := models.CreateMatrix(server.DB, newMatrix)
+// FindMatrixByFingerprint returns the caller's matrix with the given
+// fingerprint, which is shared by every rotation and mirror image.
+func (server *Server) FindMatrixByFingerprint(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	matrix := models.Matrix{}
+	matrixReceived, err := matrix.FindMatrixByFingerprint(server.DB, uid, vars["fingerprint"])
+	if err != nil {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Matrix Not Found"))
+		return
+	}
+	responses.JSON(w, http.StatusOK, matrixReceived)
+}
+
+func (server *Server) ExportPuzzLink(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	mid, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	matrix := models.Matrix{}
+	matrixReceived, err := matrix.FindMatrixByID(server.DB, mid)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	grid, err := matrixReceived.Grid()
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+	result, err := solver.FromListToState(grid)
 	if err != nil {
 		responses.ERROR(w, http.StatusInternalServerError, err)
 		return
 	}
+	state := result["state"].(*solver.FieldState)
+	responses.JSON(w, http.StatusOK, map[string]string{"url": formats.EncodePuzzLink(state)})
+}
 
-	responses.JSON(w, http.StatusOK, matrix)
+// ImportMatrices stores every puzzle file uploaded in the "files" field of
+// a multipart form. Files in any supported format are accepted; the ones
+// that fail are reported by name next to the created matrices. A puzzle the
+// caller stored before is listed with its stored matrix.
+func (server *Server) ImportMatrices(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	err = r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	created := []*models.Matrix{}
+	failed := map[string]string{}
+	for _, header := range r.MultipartForm.File["files"] {
+		file, err := header.Open()
+		if err != nil {
+			failed[header.Filename] = err.Error()
+			continue
+		}
+		data, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			failed[header.Filename] = err.Error()
+			continue
+		}
+		puzzle, err := formats.Parse(formats.DetectFormat(data), data)
+		if err != nil {
+			failed[header.Filename] = err.Error()
+			continue
+		}
+
+		source := puzzle.Metadata["source"]
+		if source == "" {
+			source = header.Filename
+		}
+		matrix := models.Matrix{UserID: uid, Source: source}
+		matrix.Prepare()
+		err = matrix.SetClues(puzzle.State.ToList())
+		if err == nil && puzzle.Solution != nil {
+			err = matrix.SetSolution(puzzle.Solution.ToList())
+		}
+		if err == nil {
+			err = matrix.SetMetadata(puzzle.Metadata)
+		}
+		if err == nil {
+			err = matrix.Validate()
+		}
+		if err != nil {
+			failed[header.Filename] = err.Error()
+			continue
+		}
+		matrixCreated, err := matrix.SaveMatrix(server.DB)
+		if err != nil && err != models.ErrDuplicateMatrix {
+			failed[header.Filename] = formaterror.FormatError(err.Error()).Error()
+			continue
+		}
+		created = append(created, matrixCreated)
+	}
+
+	status := http.StatusCreated
+	if len(created) == 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	responses.JSON(w, status, map[string]interface{}{
+		"matrices": created,
+		"errors":   failed,
+	})
 }
 
-func (server *Server) GenerateMatrix(w http.ResponseWriter, r *http.Request) {
-	size, err := strconv.Atoi(r.URL.Query().Get("size"))
+// RenderMatrix draws a matrix as SVG. "?show=solution" draws the stored
+// solution, and is not found for matrices stored without one; "?cell="
+// sets the cell size in pixels.
+func (server *Server) RenderMatrix(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	mid, err := strconv.ParseUint(vars["id"], 10, 64)
 	if err != nil {
 		responses.ERROR(w, http.StatusBadRequest, err)
 		return
 	}
+	options := render.Options{}
+	switch r.URL.Query().Get("show") {
+	case "", "puzzle":
+	case "solution":
+		options.Show = render.ShowSolution
+	default:
+		responses.ERROR(w, http.StatusBadRequest, errors.New("show must be puzzle or solution"))
+		return
+	}
+	if cell := r.URL.Query().Get("cell"); cell != "" {
+		options.CellSize, err = strconv.Atoi(cell)
+		if err != nil || options.CellSize < 8 || options.CellSize > 200 {
+			responses.ERROR(w, http.StatusBadRequest, errors.New("cell must be between 8 and 200"))
+			return
+		}
+	}
 
-	filledPercentage, err := strconv.ParseFloat(r.URL.Query().Get("filled_percentage"), 64)
+	matrix := models.Matrix{}
+	matrixReceived, err := matrix.FindMatrixByID(server.DB, mid)
 	if err != nil {
 		responses.ERROR(w, http.StatusBadRequest, err)
 		return
 	}
-
-	generator := solver.NewPuzzleGenerator(size)
-	board, err := solver.GeneratePuzzle(filledPercentage)
+	grid, err := matrixReceived.Grid()
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+	entry := render.Entry{Puzzle: result["state"].(*solver.FieldState)}
+	if options.Show == render.ShowSolution {
+		solution, err := matrixReceived.SolutionGrid()
+		if err == models.ErrNoSolution {
+			responses.ERROR(w, http.StatusNotFound, err)
+			return
+		}
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+		result, err := solver.FromListToState(solution)
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+		entry.Solution = result["state"].(*solver.FieldState)
+	}
+	board, err := render.NewBoard(entry.Puzzle, entry.Solution, options.Show)
 	if err != nil {
 		responses.ERROR(w, http.StatusInternalServerError, err)
 		return
 	}
+	var out bytes.Buffer
+	err = render.SVG(&out, board, options)
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out.Bytes())
+}
 
-	newMatrix := models.CreateMatrix{
-		Coordinates: board,
-		UserID:      auth.ExtractTokenID(r),
+// clueSymmetry parses the name of a clue layout symmetry; an empty name
+// means none.
+func clueSymmetry(name string) (solver.ClueSymmetry, error) {
+	switch name {
+	case "", "none":
+		return solver.SymmetryNone, nil
+	case "rotate180":
+		return solver.SymmetryRotate180, nil
+	case "rotate90":
+		return solver.SymmetryRotate90, nil
+	case "horizontal":
+		return solver.SymmetryHorizontal, nil
+	case "vertical":
+		return solver.SymmetryVertical, nil
+	case "diagonal":
+		return solver.SymmetryDiagonal, nil
 	}
+	return solver.SymmetryNone, errors.New("symmetry must be none, rotate180, rotate90, horizontal, vertical or diagonal")
+}
 
-	matrix, err := models.CreateMatrix(server.DB, newMatrix)
+// Limits on a booklet request. Saved matrices are only drawn, while a
+// generated booklet runs as a job, so it may hold fewer puzzles.
+const (
+	maxBookletPuzzles   = 100
+	maxBookletGenerated = 20
+)
+
+// Booklet renders a printable booklet of saved matrices ("?ids=1,2,3")
+// followed by their stored solutions. "?format=pdf" returns the whole
+// booklet, "?format=png&page=2" a single page as an image.
+//
+// "?generate=8&size=6&filled_percentage=0.3", optionally with
+// "&symmetry=rotate180" for a symmetric clue layout, queues the generation
+// of the puzzles instead and answers with the job to poll. They are stored
+// as matrices of the caller, whose IDs the finished job lists in
+// matrix_ids for a "?ids=" booklet.
+func (server *Server) Booklet(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	query := r.URL.Query()
+	options := render.BookletOptions{}
+	if perPage := query.Get("per_page"); perPage != "" {
+		options.PerPage, err = strconv.Atoi(perPage)
+		if err != nil || options.PerPage < 1 || options.PerPage > 12 {
+			responses.ERROR(w, http.StatusBadRequest, errors.New("per_page must be between 1 and 12"))
+			return
+		}
+	}
+
+	if query.Get("generate") != "" {
+		server.generateBooklet(w, r, uid)
+		return
+	}
+	if query.Get("ids") == "" {
+		responses.ERROR(w, http.StatusBadRequest, errors.New("either ids or generate is required"))
+		return
+	}
+	ids := strings.Split(query.Get("ids"), ",")
+	if len(ids) > maxBookletPuzzles {
+		responses.ERROR(w, http.StatusBadRequest, fmt.Errorf("a booklet holds at most %d puzzles", maxBookletPuzzles))
+		return
+	}
+	entries := make([]render.Entry, len(ids))
+	for i, id := range ids {
+		mid, err := strconv.ParseUint(strings.TrimSpace(id), 10, 64)
+		if err != nil {
+			responses.ERROR(w, http.StatusBadRequest, err)
+			return
+		}
+		matrix := models.Matrix{}
+		matrixReceived, err := matrix.FindMatrixByID(server.DB, mid)
+		if err != nil {
+			responses.ERROR(w, http.StatusBadRequest, err)
+			return
+		}
+		entries[i], err = storedEntry(matrixReceived)
+		if err == models.ErrNoSolution {
+			responses.ERROR(w, http.StatusUnprocessableEntity, fmt.Errorf("matrix %d has no stored solution", mid))
+			return
+		}
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	var out bytes.Buffer
+	switch query.Get("format") {
+	case "", "pdf":
+		err = render.BookletPDF(&out, entries, options)
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="booklet.pdf"`)
+	case "png":
+		pages, err := render.BookletPNG(entries, options)
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+		page := 1
+		if query.Get("page") != "" {
+			page, err = strconv.Atoi(query.Get("page"))
+			if err != nil || page < 1 || page > len(pages) {
+				responses.ERROR(w, http.StatusBadRequest, fmt.Errorf("page must be between 1 and %d", len(pages)))
+				return
+			}
+		}
+		err = png.Encode(&out, pages[page-1])
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+	default:
+		responses.ERROR(w, http.StatusBadRequest, errors.New("format must be pdf or png"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(out.Bytes())
+}
+
+// generateBooklet queues the generation of the puzzles of a booklet.
+func (server *Server) generateBooklet(w http.ResponseWriter, r *http.Request, uid uint32) {
+	query := r.URL.Query()
+	count, err := strconv.Atoi(query.Get("generate"))
+	if err != nil || count < 1 || count > maxBookletGenerated {
+		responses.ERROR(w, http.StatusBadRequest, fmt.Errorf("generate must be between 1 and %d", maxBookletGenerated))
+		return
+	}
+	size, err := strconv.Atoi(query.Get("size"))
+	if err != nil || size < 2 || size > formats.MaxSize {
+		responses.ERROR(w, http.StatusBadRequest, fmt.Errorf("size must be between 2 and %d", formats.MaxSize))
+		return
+	}
+	filledPercentage, err := strconv.ParseFloat(query.Get("filled_percentage"), 64)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	symmetry, err := clueSymmetry(query.Get("symmetry"))
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job, err := server.Jobs.Submit("booklet", uid, func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+		ids := make([]uint64, 0, count)
+		for i := 0; i < count; i++ {
+			generator := solver.NewPuzzleGenerator(size)
+			generator.SetCancel(cancel)
+			generator.SetSymmetry(symmetry)
+			generator.SetProgress(func(done, total int) {
+				report(jobs.Progress{Fraction: (float64(i) + float64(done)/float64(total)) / float64(count)})
+			})
+			board, err := generator.GeneratePuzzle(filledPercentage)
+			if err != nil {
+				return jobs.Output{}, err
+			}
+			matrix, err := generatedMatrix(uid, board, cancel)
+			if err != nil {
+				return jobs.Output{}, err
+			}
+			matrixCreated, err := matrix.SaveMatrix(server.DB)
+			if err != nil && err != models.ErrDuplicateMatrix {
+				return jobs.Output{}, err
+			}
+			ids = append(ids, matrixCreated.ID)
+		}
+		return jobs.Output{MatrixIDs: ids}, nil
+	})
+	if err == jobs.ErrQueueFull {
+		responses.ERROR(w, http.StatusServiceUnavailable, err)
+		return
+	}
 	if err != nil {
 		responses.ERROR(w, http.StatusInternalServerError, err)
 		return
 	}
+	w.Header().Set("Location", fmt.Sprintf("%s/jobs/%s", r.Host, job.ID))
+	responses.JSON(w, http.StatusAccepted, job)
+}
 
-	responses.JSON(w, http.StatusOK, matrix)
+// storedEntry pairs the clues of a matrix with its stored solution.
+func storedEntry(matrix *models.Matrix) (render.Entry, error) {
+	grid, err := matrix.Grid()
+	if err != nil {
+		return render.Entry{}, err
+	}
+	solution, err := matrix.SolutionGrid()
+	if err != nil {
+		return render.Entry{}, err
+	}
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		return render.Entry{}, err
+	}
+	puzzle := result["state"].(*solver.FieldState)
+	result, err = solver.FromListToState(solution)
+	if err != nil {
+		return render.Entry{}, err
+	}
+	return render.Entry{Puzzle: puzzle, Solution: result["state"].(*solver.FieldState)}, nil
 }
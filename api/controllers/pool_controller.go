@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/responses"
+)
+
+// GetPoolStock reports how many puzzles the pool holds for every stocked
+// size and difficulty.
+func (server *Server) GetPoolStock(w http.ResponseWriter, r *http.Request) {
+	stock, err := server.Pool.Stock()
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+	responses.JSON(w, http.StatusOK, stock)
+}
@@ -14,9 +14,35 @@ func (s *Server) initializeRoutes() {
 	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.CreateUser)).Methods("POST")
 	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.GetUsers)).Methods("GET")
 	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(s.GetUser)).Methods("GET")
+	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdateUser))).Methods("PUT")
+	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteUser)).Methods("DELETE")
 
-	//Posts routes
-	s.Router.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.CreatePost)).Methods("POST")
-	s.Router.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.GetPosts)).Methods("GET")
-	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(s.GetPost)).Methods("GET")
+	//Matrices routes
+	s.Router.HandleFunc("/matrices/solve", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.SolveMatrix))).Methods("POST")
+	s.Router.HandleFunc("/matrices/import", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.ImportPuzzLink))).Methods("POST")
+	s.Router.HandleFunc("/matrices/bulk", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.ImportMatrices))).Methods("POST")
+	s.Router.HandleFunc("/matrices/fingerprint/{fingerprint:[0-9a-f]{64}}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.FindMatrixByFingerprint))).Methods("GET")
+	s.Router.HandleFunc("/matrices/{id}/link", middlewares.SetMiddlewareJSON(s.ExportPuzzLink)).Methods("GET")
+	s.Router.HandleFunc("/matrices/{id:[0-9]+}.svg", s.RenderMatrix).Methods("GET")
+	s.Router.HandleFunc("/booklet", middlewares.SetMiddlewareAuthentication(s.Booklet)).Methods("GET")
+
+	//Jobs routes
+	s.Router.HandleFunc("/generate", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.GenerateMatrix))).Methods("POST")
+	s.Router.HandleFunc("/solve", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.SolveJob))).Methods("POST")
+	s.Router.HandleFunc("/jobs/{id:[0-9a-f]+}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.GetJob))).Methods("GET")
+	s.Router.HandleFunc("/jobs/{id:[0-9a-f]+}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.CancelJob))).Methods("DELETE")
+	s.Router.HandleFunc("/jobs/{id:[0-9a-f]+}/events", middlewares.SetMiddlewareAuthentication(s.JobEvents)).Methods("GET")
+
+	//Live routes
+	s.Router.HandleFunc("/solve/live", middlewares.SetMiddlewareAuthentication(s.LiveSolve)).Methods("GET")
+
+	//Pool routes
+	s.Router.HandleFunc("/pool", middlewares.SetMiddlewareJSON(s.GetPoolStock)).Methods("GET")
+
+	//Daily routes
+	s.Router.HandleFunc("/daily", middlewares.SetMiddlewareJSON(s.GetDaily)).Methods("GET")
+	s.Router.HandleFunc("/daily/{date}", middlewares.SetMiddlewareJSON(s.GetDailyByDate)).Methods("GET")
+
+	//Batch routes
+	s.Router.HandleFunc("/batch", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.SolveBatch))).Methods("POST")
 }
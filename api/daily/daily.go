@@ -0,0 +1,246 @@
+// Package daily picks the puzzle of the day: the same puzzle for every
+// player, chosen once per date and kept for good.
+package daily
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/pool"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// DateLayout is how dates are written in the API and the store.
+const DateLayout = "2006-01-02"
+
+// Puzzle is the puzzle of one date.
+type Puzzle struct {
+	Date       string  `json:"date"`
+	Size       int     `json:"size"`
+	Difficulty string  `json:"difficulty"`
+	Grid       [][]int `json:"grid"`
+}
+
+// Store keeps the puzzle of every date.
+type Store interface {
+	// FindDaily returns the puzzle of a date, or fails with ErrNotFound.
+	FindDaily(date string) (Puzzle, error)
+	// SaveDaily records the puzzle of a date unless the date has one
+	// already, and returns the puzzle the date ends up with.
+	SaveDaily(puzzle Puzzle) (Puzzle, error)
+}
+
+var (
+	// ErrNotFound is returned for dates without a puzzle.
+	ErrNotFound = errors.New("no puzzle for that date")
+	// ErrNotYet is returned by Get for dates after today, whose puzzles
+	// may be picked already but are not shown.
+	ErrNotYet = errors.New("the puzzle of that date is not out yet")
+	// ErrNotReady is returned by Get for today while the worker has not
+	// picked its puzzle yet.
+	ErrNotReady = errors.New("the puzzle of the day is not ready yet, try again shortly")
+)
+
+// Options configures a Scheduler. Zero values pick the defaults.
+type Options struct {
+	// Size and Difficulty are the kind of puzzle picked every day.
+	Size       int
+	Difficulty string
+	// Pool, when it stocks the size and difficulty, is where puzzles are
+	// taken from before generating one.
+	Pool *pool.Pool
+	// Attempts bounds the solutions drawn when generating, see
+	// solver.PuzzleGenerator.GenerateRated.
+	Attempts int
+	// Location sets where days start, UTC unless set.
+	Location *time.Location
+	// Store keeps the puzzles, in memory unless set.
+	Store Store
+}
+
+const (
+	defaultSize     = 7
+	defaultAttempts = 50
+	// retryInterval is the wait before trying again when a day's puzzle
+	// could not be picked.
+	retryInterval = time.Minute
+)
+
+// Scheduler picks the puzzle of every day. Its worker picks the puzzles of
+// today and tomorrow ahead of time; Get only serves what it picked, so a
+// request never waits on a puzzle being generated.
+type Scheduler struct {
+	options Options
+	now     func() time.Time
+
+	// mu serializes picking, so a date is never generated for twice.
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewScheduler makes a scheduler. Its worker only runs once Start is
+// called.
+func NewScheduler(options Options) *Scheduler {
+	if options.Size <= 0 {
+		options.Size = defaultSize
+	}
+	if options.Difficulty == "" {
+		options.Difficulty = solver.DifficultyMedium
+	}
+	if options.Attempts <= 0 {
+		options.Attempts = defaultAttempts
+	}
+	if options.Location == nil {
+		options.Location = time.UTC
+	}
+	if options.Store == nil {
+		options.Store = NewMemoryStore()
+	}
+	return &Scheduler{
+		options: options,
+		now:     time.Now,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// SetClock replaces the clock the scheduler reads today from.
+func (s *Scheduler) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Today returns the date of today.
+func (s *Scheduler) Today() string {
+	return s.now().In(s.options.Location).Format(DateLayout)
+}
+
+// Get returns the puzzle of a date up to today. Dates before the first
+// picked puzzle have none, and today has none until the worker picked it.
+func (s *Scheduler) Get(date string) (Puzzle, error) {
+	day, err := time.ParseInLocation(DateLayout, date, s.options.Location)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	today := s.Today()
+	date = day.Format(DateLayout)
+	if date > today {
+		return Puzzle{}, ErrNotYet
+	}
+	puzzle, err := s.options.Store.FindDaily(date)
+	if err == ErrNotFound && date == today {
+		return Puzzle{}, ErrNotReady
+	}
+	return puzzle, err
+}
+
+// Pick returns the puzzle of a date, taking or generating it if the date
+// has none yet.
+func (s *Scheduler) Pick(date string) (Puzzle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	puzzle, err := s.options.Store.FindDaily(date)
+	if err != ErrNotFound {
+		return puzzle, err
+	}
+	grid, err := s.board()
+	if err != nil {
+		return Puzzle{}, err
+	}
+	return s.options.Store.SaveDaily(Puzzle{
+		Date:       date,
+		Size:       s.options.Size,
+		Difficulty: s.options.Difficulty,
+		Grid:       grid,
+	})
+}
+
+func (s *Scheduler) board() ([][]int, error) {
+	if s.options.Pool != nil {
+		puzzle, err := s.options.Pool.Take(pool.Bucket{Size: s.options.Size, Difficulty: s.options.Difficulty})
+		if err == nil {
+			return puzzle.Clues, nil
+		}
+		if err != pool.ErrEmpty && err != pool.ErrUnknownBucket {
+			return nil, err
+		}
+	}
+	generator := solver.NewPuzzleGenerator(s.options.Size)
+	generator.SetCancel(s.stop)
+	return generator.GenerateRated(s.options.Difficulty, s.options.Attempts)
+}
+
+// Start runs the worker, which picks the puzzles of today and tomorrow
+// and then sleeps until the next day starts.
+func (s *Scheduler) Start() {
+	go func() {
+		defer close(s.done)
+		for {
+			wait := s.untilTomorrow()
+			now := s.now().In(s.options.Location)
+			for _, day := range []time.Time{now, now.AddDate(0, 0, 1)} {
+				_, err := s.Pick(day.Format(DateLayout))
+				if err == solver.ErrCancelled {
+					return
+				}
+				if err != nil {
+					log.Printf("picking the puzzle of %s: %v", day.Format(DateLayout), err)
+					wait = retryInterval
+				}
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-s.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// untilTomorrow is the time left until the next day starts.
+func (s *Scheduler) untilTomorrow() time.Duration {
+	now := s.now().In(s.options.Location)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, s.options.Location)
+	return tomorrow.Sub(now)
+}
+
+// Stop ends the worker of a started scheduler and waits for it.
+func (s *Scheduler) Stop() {
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// MemoryStore is a Store that keeps the puzzles in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	puzzles map[string]Puzzle
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{puzzles: make(map[string]Puzzle)}
+}
+
+func (m *MemoryStore) FindDaily(date string) (Puzzle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	puzzle, found := m.puzzles[date]
+	if !found {
+		return Puzzle{}, ErrNotFound
+	}
+	return puzzle, nil
+}
+
+func (m *MemoryStore) SaveDaily(puzzle Puzzle) (Puzzle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, found := m.puzzles[puzzle.Date]; found {
+		return existing, nil
+	}
+	m.puzzles[puzzle.Date] = puzzle
+	return puzzle, nil
+}
@@ -0,0 +1,148 @@
+package formats
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// BoxStyle selects the characters used by Render.
+type BoxStyle int
+
+const (
+	// BoxASCII draws borders with "+", "-" and "|".
+	BoxASCII BoxStyle = iota
+	// BoxUnicode draws borders with heavy box-drawing characters.
+	BoxUnicode
+)
+
+// heavyJunctions maps the arms of a junction (up, down, left, right as bits
+// 1, 2, 4, 8) to a box-drawing character.
+var heavyJunctions = [16]string{
+	" ", "╹", "╻", "┃", "╸", "┛", "┓", "┫",
+	"╺", "┗", "┏", "┣", "━", "┻", "┳", "╋",
+}
+
+// Render draws the board with a border around every region and around the
+// explicit walls of the puzzle. Two neighbouring cells are in different
+// regions when both hold numbers and the numbers differ; blanks only get
+// the outer frame and explicit walls.
+func (p *Puzzle) Render(style BoxStyle) string {
+	return p.RenderCells(style, nil)
+}
+
+// RenderCells is Render with a hook that may wrap the padded text of every
+// cell, for instance in terminal escape codes. A nil decorate leaves the
+// cells as they are.
+func (p *Puzzle) RenderCells(style BoxStyle, decorate func(cell solver.Cell, text string) string) string {
+	grid := p.State.ToList()
+	size := len(grid)
+	walls := wallSet(p.Walls)
+
+	width := 1
+	for _, row := range grid {
+		for _, value := range row {
+			if n := len(strconv.Itoa(value)); n > width {
+				width = n
+			}
+		}
+	}
+	width += 2
+
+	// vertical[x][y] is the border left of cell (x, y), y in 0..size.
+	// horizontal[x][y] is the border above cell (x, y), x in 0..size.
+	vertical := make([][]bool, size)
+	for x := range vertical {
+		vertical[x] = make([]bool, size+1)
+		vertical[x][0], vertical[x][size] = true, true
+		for y := 1; y < size; y++ {
+			vertical[x][y] = separated(grid, walls, solver.Cell{X: x, Y: y - 1}, solver.Cell{X: x, Y: y})
+		}
+	}
+	horizontal := make([][]bool, size+1)
+	for x := range horizontal {
+		horizontal[x] = make([]bool, size)
+		for y := range horizontal[x] {
+			if x == 0 || x == size {
+				horizontal[x][y] = true
+				continue
+			}
+			horizontal[x][y] = separated(grid, walls, solver.Cell{X: x - 1, Y: y}, solver.Cell{X: x, Y: y})
+		}
+	}
+
+	var b strings.Builder
+	for x := 0; x <= size; x++ {
+		for y := 0; y <= size; y++ {
+			up := x > 0 && vertical[x-1][y]
+			down := x < size && vertical[x][y]
+			left := y > 0 && horizontal[x][y-1]
+			right := y < size && horizontal[x][y]
+			b.WriteString(junction(style, up, down, left, right))
+			if y < size {
+				b.WriteString(strings.Repeat(edge(style, horizontal[x][y], true), width))
+			}
+		}
+		b.WriteString("\n")
+		if x == size {
+			break
+		}
+		for y := 0; y <= size; y++ {
+			b.WriteString(edge(style, vertical[x][y], false))
+			if y < size {
+				label := "."
+				if grid[x][y] != 0 {
+					label = strconv.Itoa(grid[x][y])
+				}
+				pad := width - len(label)
+				text := strings.Repeat(" ", pad-pad/2) + label + strings.Repeat(" ", pad/2)
+				if decorate != nil {
+					text = decorate(solver.Cell{X: x, Y: y}, text)
+				}
+				b.WriteString(text)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func separated(grid [][]int, walls map[Wall]bool, a, b solver.Cell) bool {
+	if walls[Wall{a, b}] {
+		return true
+	}
+	va, vb := grid[a.X][a.Y], grid[b.X][b.Y]
+	return va != 0 && vb != 0 && va != vb
+}
+
+func junction(style BoxStyle, up, down, left, right bool) string {
+	arms := 0
+	for bit, on := range []bool{up, down, left, right} {
+		if on {
+			arms |= 1 << uint(bit)
+		}
+	}
+	if style == BoxUnicode {
+		return heavyJunctions[arms]
+	}
+	if arms == 0 {
+		return " "
+	}
+	return "+"
+}
+
+func edge(style BoxStyle, wall, horizontal bool) string {
+	switch {
+	case !wall:
+		return " "
+	case style == BoxUnicode && horizontal:
+		return "━"
+	case style == BoxUnicode:
+		return "┃"
+	case horizontal:
+		return "-"
+	default:
+		return "|"
+	}
+}
@@ -0,0 +1,124 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format names a puzzle file format.
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatPuzzLink Format = "puzzlink"
+	FormatJanko    Format = "janko"
+	FormatPzpr     Format = "pzprv3"
+	FormatKudamono Format = "kudamono"
+)
+
+// Formats lists every supported format.
+var Formats = []Format{FormatText, FormatPuzzLink, FormatJanko, FormatPzpr, FormatKudamono}
+
+// DetectFormat guesses the format of a puzzle file from its content.
+func DetectFormat(data []byte) Format {
+	text := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(text, "pzprv3"):
+		return FormatPzpr
+	case strings.HasPrefix(text, "["), strings.Contains(text, "\n[problem]"):
+		return FormatJanko
+	case !strings.Contains(text, "\n") && strings.Contains(text, "paper-puzzle-player"):
+		return FormatKudamono
+	case !strings.Contains(text, "\n") && strings.Contains(text, "fillomino/"):
+		return FormatPuzzLink
+	default:
+		return FormatText
+	}
+}
+
+// Parse reads one puzzle in the given format.
+func Parse(format Format, data []byte) (*Puzzle, error) {
+	switch format {
+	case FormatText:
+		return ParseText(bytes.NewReader(data))
+	case FormatPuzzLink:
+		state, err := DecodePuzzLink(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, err
+		}
+		return &Puzzle{State: state, Metadata: make(map[string]string)}, nil
+	case FormatJanko:
+		return ParseJanko(bytes.NewReader(data))
+	case FormatPzpr:
+		return ParsePzprFile(bytes.NewReader(data))
+	case FormatKudamono:
+		state, err := DecodeKudamono(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, err
+		}
+		return &Puzzle{State: state, Metadata: make(map[string]string)}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// Write writes one puzzle in the given format.
+func Write(format Format, w io.Writer, puzzle *Puzzle) error {
+	switch format {
+	case FormatText:
+		return WriteText(w, puzzle)
+	case FormatPuzzLink:
+		_, err := fmt.Fprintln(w, EncodePuzzLink(puzzle.State))
+		return err
+	case FormatJanko:
+		return WriteJanko(w, puzzle)
+	case FormatPzpr:
+		return WritePzprFile(w, puzzle)
+	case FormatKudamono:
+		_, err := fmt.Fprintln(w, EncodeKudamono(puzzle.State))
+		return err
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// ReadFile reads a puzzle file in any supported format. The path is
+// recorded in the "source" metadata unless the file names its own source.
+func ReadFile(path string) (*Puzzle, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	puzzle, err := Parse(DetectFormat(data), data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if _, ok := puzzle.Metadata["source"]; !ok {
+		puzzle.Metadata["source"] = path
+	}
+	return puzzle, nil
+}
+
+// ReadDir reads every regular file below dir as a puzzle, in lexical order.
+// Files that fail to parse are reported in errs without stopping the walk.
+func ReadDir(dir string) (puzzles []*Puzzle, errs []error, err error) {
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		puzzle, err := ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		puzzles = append(puzzles, puzzle)
+		return nil
+	})
+	return puzzles, errs, err
+}
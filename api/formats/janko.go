@@ -0,0 +1,146 @@
+package formats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// ParseJanko reads a puzzle in the text format of the janko.at archive:
+//
+//	[setup]
+//	author = Otto Janko
+//	puzzle = fillomino
+//	size   = 5
+//	[problem]
+//	- 3 - - 1
+//	...
+//	[solution]
+//	2 3 3 3 1
+//	...
+//	[end]
+//
+// Setup keys become metadata. Blanks are "-" and the solution section is
+// optional; other sections such as [moves] are skipped.
+func ParseJanko(r io.Reader) (*Puzzle, error) {
+	metadata := make(map[string]string)
+	sections := make(map[string][][]string)
+	section := ""
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			section = strings.ToLower(strings.Trim(text, "[]"))
+			continue
+		}
+		switch section {
+		case "setup":
+			if i := strings.Index(text, "="); i > 0 {
+				metadata[strings.ToLower(strings.TrimSpace(text[:i]))] = strings.TrimSpace(text[i+1:])
+			}
+		case "problem", "solution":
+			sections[section] = append(sections[section], strings.Fields(text))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if kind, ok := metadata["puzzle"]; ok && !strings.EqualFold(kind, "fillomino") {
+		return nil, fmt.Errorf("not a fillomino puzzle: %s", kind)
+	}
+	if len(sections["problem"]) == 0 {
+		return nil, errors.New("missing [problem] section")
+	}
+
+	state, err := jankoGrid(sections["problem"])
+	if err != nil {
+		return nil, fmt.Errorf("problem: %v", err)
+	}
+	delete(metadata, "puzzle")
+	puzzle := &Puzzle{State: state, Metadata: metadata}
+	if rows, ok := sections["solution"]; ok {
+		puzzle.Solution, err = jankoGrid(rows)
+		if err != nil {
+			return nil, fmt.Errorf("solution: %v", err)
+		}
+	}
+	return puzzle, nil
+}
+
+func jankoGrid(rows [][]string) (*solver.FieldState, error) {
+	if err := checkSize(len(rows), len(rows)); err != nil {
+		return nil, err
+	}
+	grid := make([][]int, len(rows))
+	for x, row := range rows {
+		if len(row) != len(rows) {
+			return nil, fmt.Errorf("row %d has %d cells, expected %d", x+1, len(row), len(rows))
+		}
+		grid[x] = make([]int, len(row))
+		for y, token := range row {
+			if token == "-" {
+				continue
+			}
+			value, err := strconv.Atoi(token)
+			if err != nil || value < 1 {
+				return nil, fmt.Errorf("invalid cell %q", token)
+			}
+			grid[x][y] = value
+		}
+	}
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		return nil, err
+	}
+	return result["state"].(*solver.FieldState), nil
+}
+
+// WriteJanko writes a puzzle in the janko.at text format.
+func WriteJanko(w io.Writer, puzzle *Puzzle) error {
+	var b strings.Builder
+	grid := puzzle.State.ToList()
+	b.WriteString("[setup]\n")
+	fmt.Fprintf(&b, "puzzle = fillomino\nsize = %d\n", len(grid))
+	keys := make([]string, 0, len(puzzle.Metadata))
+	for key := range puzzle.Metadata {
+		if key != "puzzle" && key != "size" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s = %s\n", key, puzzle.Metadata[key])
+	}
+	b.WriteString("[problem]\n")
+	writeJankoGrid(&b, grid)
+	if puzzle.Solution != nil {
+		b.WriteString("[solution]\n")
+		writeJankoGrid(&b, puzzle.Solution.ToList())
+	}
+	b.WriteString("[end]\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeJankoGrid(b *strings.Builder, grid [][]int) {
+	for _, row := range grid {
+		cells := make([]string, len(row))
+		for y, value := range row {
+			cells[y] = "-"
+			if value != 0 {
+				cells[y] = strconv.Itoa(value)
+			}
+		}
+		b.WriteString(strings.Join(cells, " ") + "\n")
+	}
+}
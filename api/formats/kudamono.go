@@ -0,0 +1,110 @@
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// KudamonoBase is the prefix of the links produced by EncodeKudamono.
+const KudamonoBase = "https://pedros.works/paper-puzzle-player"
+
+// DecodeKudamono reads a Fillomino link of Kudamono's paper puzzle player,
+// such as
+//
+//	https://pedros.works/paper-puzzle-player?W=4x4&L=3(3)2(4)1(1)6(2)&G=fillomino
+//
+// into a field state. "W" is the board size, as columns "x" rows or a
+// single side, and "G" the genre. "L" lists the clues: each is the number
+// of cells it lies after the previous clue followed by its value in
+// parentheses. Kudamono counts cells column by column, each column from
+// the bottom up, starting at the bottom left corner; the first clue counts
+// from just before that corner.
+func DecodeKudamono(link string) (*solver.FieldState, error) {
+	query := link
+	if start := strings.Index(link, "?"); start != -1 {
+		query = link[start+1:]
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if params.Get("G") != "fillomino" {
+		return nil, errors.New("not a fillomino link")
+	}
+	size := params.Get("W")
+	if size == "" {
+		return nil, errors.New("link is missing the board size")
+	}
+	dims := strings.SplitN(size, "x", 2)
+	if len(dims) == 1 {
+		dims = append(dims, dims[0])
+	}
+	cols, err := strconv.Atoi(dims[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid column count %q", dims[0])
+	}
+	rows, err := strconv.Atoi(dims[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid row count %q", dims[1])
+	}
+	if err := checkSize(cols, rows); err != nil {
+		return nil, err
+	}
+
+	field, err := solver.NewField(rows)
+	if err != nil {
+		return nil, err
+	}
+	state := solver.NewFieldState(field)
+	labels := params.Get("L")
+	position := -1
+	for i := 0; i < len(labels); {
+		open := strings.IndexByte(labels[i:], '(')
+		if open == -1 {
+			return nil, fmt.Errorf("clue list ends with %q", labels[i:])
+		}
+		end := strings.IndexByte(labels[i+open:], ')')
+		if end == -1 {
+			return nil, errors.New("clue list ends inside a clue")
+		}
+		step, err := strconv.Atoi(labels[i : i+open])
+		if err != nil || step < 1 {
+			return nil, fmt.Errorf("invalid clue offset %q", labels[i:i+open])
+		}
+		text := labels[i+open+1 : i+open+end]
+		value, err := strconv.Atoi(text)
+		if err != nil || value < 1 {
+			return nil, fmt.Errorf("clue %q is not supported", text)
+		}
+		position += step
+		if position >= rows*cols {
+			return nil, fmt.Errorf("clue %q lies outside the board", text)
+		}
+		state.SetState(solver.Cell{X: rows - 1 - position%rows, Y: position / rows}, value)
+		i += open + end + 1
+	}
+	return state, nil
+}
+
+// EncodeKudamono writes the givens of a field state as a link of
+// Kudamono's paper puzzle player.
+func EncodeKudamono(state *solver.FieldState) string {
+	grid := state.ToList()
+	size := len(grid)
+	var b strings.Builder
+	last := -1
+	for position := 0; position < size*size; position++ {
+		value := grid[size-1-position%size][position/size]
+		if value <= 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%d(%d)", position-last, value)
+		last = position
+	}
+	return fmt.Sprintf("%s?W=%dx%d&L=%s&G=fillomino", KudamonoBase, size, size, b.String())
+}
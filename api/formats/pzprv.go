@@ -0,0 +1,170 @@
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// PuzzLinkBase is the prefix of the links produced by EncodePuzzLink.
+const PuzzLinkBase = "https://puzz.link/p?fillomino/"
+
+// MaxSize is the largest board side the readers accept.
+const MaxSize = 40
+
+// checkSize rejects board sizes the readers do not handle before anything
+// is allocated for them.
+func checkSize(cols, rows int) error {
+	if cols < 1 || rows < 1 {
+		return fmt.Errorf("invalid board size %dx%d", cols, rows)
+	}
+	if cols != rows {
+		return fmt.Errorf("only square boards are supported, got %dx%d", cols, rows)
+	}
+	if cols > MaxSize {
+		return fmt.Errorf("boards larger than %dx%d are not supported, got %dx%d", MaxSize, MaxSize, cols, rows)
+	}
+	return nil
+}
+
+// DecodePuzzLink reads a puzz.link or pzprv3 Fillomino URL, such as
+// https://puzz.link/p?fillomino/4/4/h2g3j1i4h, into a field state. Only the
+// part after "fillomino/" is looked at, so pzv.jp links work as well.
+func DecodePuzzLink(link string) (*solver.FieldState, error) {
+	start := strings.Index(link, "fillomino/")
+	if start == -1 {
+		return nil, errors.New("not a fillomino link")
+	}
+	parts := strings.Split(link[start+len("fillomino/"):], "/")
+	if len(parts) > 0 && strings.Contains(parts[0], ":") {
+		// Optional pzpr flags such as "v:" come before the size.
+		parts = parts[1:]
+	}
+	if len(parts) < 3 {
+		return nil, errors.New("link is missing the board size or body")
+	}
+	cols, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid column count %q", parts[0])
+	}
+	rows, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid row count %q", parts[1])
+	}
+	if err := checkSize(cols, rows); err != nil {
+		return nil, err
+	}
+
+	values, err := decodeNumber16(parts[2], rows*cols)
+	if err != nil {
+		return nil, err
+	}
+	field, err := solver.NewField(rows)
+	if err != nil {
+		return nil, err
+	}
+	state := solver.NewFieldState(field)
+	for i, value := range values {
+		state.SetState(solver.Cell{X: i / cols, Y: i % cols}, value)
+	}
+	return state, nil
+}
+
+// EncodePuzzLink writes the givens of a field state as a puzz.link URL.
+func EncodePuzzLink(state *solver.FieldState) string {
+	grid := state.ToList()
+	size := len(grid)
+	values := make([]int, 0, size*size)
+	for _, row := range grid {
+		values = append(values, row...)
+	}
+	return fmt.Sprintf("%s%d/%d/%s", PuzzLinkBase, size, size, encodeNumber16(values))
+}
+
+// decodeNumber16 follows pzpr's Encode.decodeNumber16: one hex digit for
+// 0-15, "-" plus two digits up to 255, "+" plus three digits up to 4095,
+// "=" and "%" for the two ranges above, and "g" to "z" for runs of 1 to 20
+// empty cells.
+func decodeNumber16(body string, cells int) ([]int, error) {
+	values := make([]int, cells)
+	c := 0
+	for i := 0; i < len(body) && c < cells; i++ {
+		ch := body[i]
+		width, offset := 0, 0
+		switch {
+		case ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'f':
+			values[c] = int(hexValue(ch))
+		case ch == '-':
+			width = 2
+		case ch == '+':
+			width = 3
+		case ch == '=':
+			width, offset = 3, 4096
+		case ch == '%':
+			width, offset = 3, 8192
+		case ch == '.':
+			return nil, fmt.Errorf("question mark clue at cell %d is not supported", c)
+		case ch >= 'g' && ch <= 'z':
+			c += int(ch-'g') + 1
+			continue
+		default:
+			return nil, fmt.Errorf("unexpected character %q in link body", ch)
+		}
+		if width > 0 {
+			if i+width >= len(body) {
+				return nil, errors.New("link body ends inside a number")
+			}
+			n, err := strconv.ParseInt(body[i+1:i+1+width], 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q in link body", body[i+1:i+1+width])
+			}
+			values[c] = int(n) + offset
+			i += width
+		}
+		c++
+	}
+	return values, nil
+}
+
+func encodeNumber16(values []int) string {
+	var b strings.Builder
+	count := 0
+	for _, value := range values {
+		piece := ""
+		switch {
+		case value <= 0:
+			count++
+		case value < 16:
+			piece = strconv.FormatInt(int64(value), 16)
+		case value < 256:
+			piece = "-" + strconv.FormatInt(int64(value), 16)
+		case value < 4096:
+			piece = "+" + strconv.FormatInt(int64(value), 16)
+		case value < 8192:
+			piece = fmt.Sprintf("=%03x", value-4096)
+		default:
+			piece = fmt.Sprintf("%%%03x", value-8192)
+		}
+		if count == 0 {
+			b.WriteString(piece)
+		} else if piece != "" || count == 20 {
+			b.WriteString(strconv.FormatInt(int64(15+count), 36))
+			b.WriteString(piece)
+			count = 0
+		}
+	}
+	if count > 0 {
+		b.WriteString(strconv.FormatInt(int64(15+count), 36))
+	}
+	return b.String()
+}
+
+func hexValue(ch byte) byte {
+	if ch <= '9' {
+		return ch - '0'
+	}
+	return ch - 'a' + 10
+}
@@ -0,0 +1,178 @@
+package formats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// ParsePzprFile reads the pzprv3 file format saved by pzv.jp and puzz.link:
+//
+//	pzprv3
+//	fillomino
+//	4
+//	4
+//	. 2 . .
+//	3 . +3 .
+//	...
+//
+// After the header come one line per row of cells and, optionally, the
+// border lines: a row of vertical borders per board row followed by a row
+// of horizontal borders between each pair of board rows, with 1 marking a
+// border. A plain number is a given, "+n" an answer entered by the solver
+// and "." an empty cell.
+func ParsePzprFile(r io.Reader) (*Puzzle, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if text := strings.TrimSpace(scanner.Text()); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) < 4 || lines[0] != "pzprv3" {
+		return nil, errors.New("missing pzprv3 header")
+	}
+	if lines[1] != "fillomino" {
+		return nil, fmt.Errorf("not a fillomino puzzle: %s", lines[1])
+	}
+	rows, err := strconv.Atoi(lines[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid row count %q", lines[2])
+	}
+	cols, err := strconv.Atoi(lines[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid column count %q", lines[3])
+	}
+	if err := checkSize(cols, rows); err != nil {
+		return nil, err
+	}
+	body := lines[4:]
+	if len(body) < rows {
+		return nil, errors.New("file ends before the last row of cells")
+	}
+
+	givens := make([][]int, rows)
+	answers := make([][]int, rows)
+	hasAnswers := false
+	for x := 0; x < rows; x++ {
+		tokens := strings.Fields(body[x])
+		if len(tokens) != cols {
+			return nil, fmt.Errorf("row %d has %d cells, expected %d", x+1, len(tokens), cols)
+		}
+		givens[x] = make([]int, cols)
+		answers[x] = make([]int, cols)
+		for y, token := range tokens {
+			if i := strings.Index(token, "["); i != -1 {
+				// Pencil marks are kept by pzpr as a bracketed suffix.
+				token = token[:i]
+			}
+			switch {
+			case token == "." || token == "":
+			case token == "-":
+				return nil, fmt.Errorf("question mark clue in row %d is not supported", x+1)
+			case strings.HasPrefix(token, "+"):
+				value, err := strconv.Atoi(token[1:])
+				if err != nil || value < 1 {
+					return nil, fmt.Errorf("invalid answer %q", token)
+				}
+				answers[x][y] = value
+				hasAnswers = true
+			default:
+				value, err := strconv.Atoi(token)
+				if err != nil || value < 1 {
+					return nil, fmt.Errorf("invalid cell %q", token)
+				}
+				givens[x][y] = value
+				answers[x][y] = value
+			}
+		}
+	}
+
+	result, err := solver.FromListToState(givens)
+	if err != nil {
+		return nil, err
+	}
+	puzzle := &Puzzle{State: result["state"].(*solver.FieldState), Metadata: make(map[string]string)}
+	if hasAnswers {
+		result, err = solver.FromListToState(answers)
+		if err != nil {
+			return nil, err
+		}
+		puzzle.Solution = result["state"].(*solver.FieldState)
+	}
+
+	borders := body[rows:]
+	for x := 0; x < rows && x < len(borders); x++ {
+		for y, token := range strings.Fields(borders[x]) {
+			if token == "1" && y+1 < cols {
+				puzzle.Walls = append(puzzle.Walls, Wall{solver.Cell{X: x, Y: y}, solver.Cell{X: x, Y: y + 1}})
+			}
+		}
+	}
+	for x := 0; x+1 < rows && rows+x < len(borders); x++ {
+		for y, token := range strings.Fields(borders[rows+x]) {
+			if token == "1" && y < cols {
+				puzzle.Walls = append(puzzle.Walls, Wall{solver.Cell{X: x, Y: y}, solver.Cell{X: x + 1, Y: y}})
+			}
+		}
+	}
+	return puzzle, nil
+}
+
+// WritePzprFile writes a puzzle in the pzprv3 file format. Solution values
+// that are not givens are written as answers and explicit walls as borders.
+func WritePzprFile(w io.Writer, puzzle *Puzzle) error {
+	var b strings.Builder
+	grid := puzzle.State.ToList()
+	size := len(grid)
+	var answers [][]int
+	if puzzle.Solution != nil {
+		answers = puzzle.Solution.ToList()
+	}
+	fmt.Fprintf(&b, "pzprv3\nfillomino\n%d\n%d\n", size, size)
+	for x, row := range grid {
+		for y, value := range row {
+			switch {
+			case value != 0:
+				b.WriteString(strconv.Itoa(value))
+			case answers != nil && answers[x][y] != 0:
+				b.WriteString("+" + strconv.Itoa(answers[x][y]))
+			default:
+				b.WriteString(".")
+			}
+			b.WriteString(" ")
+		}
+		b.WriteString("\n")
+	}
+
+	walls := wallSet(puzzle.Walls)
+	for x := 0; x < size; x++ {
+		for y := 0; y+1 < size; y++ {
+			b.WriteString(borderToken(walls[Wall{solver.Cell{X: x, Y: y}, solver.Cell{X: x, Y: y + 1}}]))
+		}
+		b.WriteString("\n")
+	}
+	for x := 0; x+1 < size; x++ {
+		for y := 0; y < size; y++ {
+			b.WriteString(borderToken(walls[Wall{solver.Cell{X: x, Y: y}, solver.Cell{X: x + 1, Y: y}}]))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func borderToken(wall bool) string {
+	if wall {
+		return "1 "
+	}
+	return "0 "
+}
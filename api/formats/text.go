@@ -0,0 +1,244 @@
+package formats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// Puzzle is a board read from one of the text formats together with what
+// the format carries besides the numbers.
+type Puzzle struct {
+	State    *solver.FieldState
+	Metadata map[string]string
+	// Solution is set when the source also carries the answer.
+	Solution *solver.FieldState
+	// Walls are borders given explicitly by the source, in addition to the
+	// ones implied by differing numbers.
+	Walls []Wall
+}
+
+// Wall separates two orthogonally adjacent cells.
+type Wall struct {
+	A, B solver.Cell
+}
+
+// ParseText reads the plain-text format:
+//
+//	# comments start with a hash
+//	title: Example
+//	author: someone
+//	3.|.1
+//	--...
+//	.2..4
+//
+// Header lines ("key: value") come before the grid. A grid row is either a
+// run of single characters, one per cell, with no spaces, or
+// whitespace-separated numbers when values need more than one digit; "."
+// marks a blank. A "|" between two cells is a wall, and a line of "-" and
+// "." under a row, one mark per cell, puts walls below the marked cells.
+func ParseText(r io.Reader) (*Puzzle, error) {
+	metadata := make(map[string]string)
+	var rows [][]int
+	var vertical [][2]int
+	var horizontal [][2]int
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(text, "#"); i != -1 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			continue
+		}
+		if key, value, ok := headerLine(text); ok {
+			if len(rows) > 0 {
+				return nil, fmt.Errorf("line %d: header after the grid", line)
+			}
+			metadata[key] = value
+			continue
+		}
+		if strings.Contains(text, "-") {
+			if len(rows) == 0 {
+				return nil, fmt.Errorf("line %d: wall line before the first row", line)
+			}
+			marks := splitCells(text)
+			for y, mark := range marks {
+				switch mark {
+				case "-":
+					horizontal = append(horizontal, [2]int{len(rows) - 1, y})
+				case ".":
+				default:
+					return nil, fmt.Errorf("line %d: unexpected wall mark %q", line, mark)
+				}
+			}
+			continue
+		}
+
+		var row []int
+		for _, token := range splitCells(text) {
+			if token == "|" {
+				if len(row) == 0 {
+					return nil, fmt.Errorf("line %d: wall before the first cell", line)
+				}
+				vertical = append(vertical, [2]int{len(rows), len(row) - 1})
+				continue
+			}
+			value, err := cellValue(token)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no grid found")
+	}
+
+	size := len(rows)
+	if err := checkSize(size, size); err != nil {
+		return nil, err
+	}
+	for x, row := range rows {
+		if len(row) != size {
+			return nil, fmt.Errorf("row %d has %d cells, expected %d", x+1, len(row), size)
+		}
+	}
+	result, err := solver.FromListToState(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	puzzle := &Puzzle{State: result["state"].(*solver.FieldState), Metadata: metadata}
+	for _, w := range vertical {
+		if w[1]+1 >= size {
+			return nil, fmt.Errorf("wall after the last cell of row %d", w[0]+1)
+		}
+		puzzle.Walls = append(puzzle.Walls, Wall{solver.Cell{X: w[0], Y: w[1]}, solver.Cell{X: w[0], Y: w[1] + 1}})
+	}
+	for _, w := range horizontal {
+		if w[0]+1 >= size || w[1] >= size {
+			return nil, fmt.Errorf("wall below row %d is outside the board", w[0]+1)
+		}
+		puzzle.Walls = append(puzzle.Walls, Wall{solver.Cell{X: w[0], Y: w[1]}, solver.Cell{X: w[0] + 1, Y: w[1]}})
+	}
+	return puzzle, nil
+}
+
+func headerLine(text string) (string, string, bool) {
+	i := strings.Index(text, ":")
+	if i <= 0 {
+		return "", "", false
+	}
+	key := strings.TrimSpace(text[:i])
+	for _, r := range key {
+		if !(r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return "", "", false
+		}
+	}
+	return strings.ToLower(key), strings.TrimSpace(text[i+1:]), true
+}
+
+// splitCells breaks a row into cell tokens and "|" walls. Rows that still
+// contain whitespace once walls are removed are split on it; other rows are
+// read one character per cell.
+func splitCells(text string) []string {
+	if strings.ContainsAny(strings.Replace(text, "|", "", -1), " \t") {
+		return strings.Fields(strings.Replace(text, "|", " | ", -1))
+	}
+	var tokens []string
+	for _, r := range text {
+		tokens = append(tokens, string(r))
+	}
+	return tokens
+}
+
+func cellValue(token string) (int, error) {
+	if token == "." {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(token)
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("invalid cell %q", token)
+	}
+	return value, nil
+}
+
+// WriteText writes a puzzle in the format read by ParseText. Metadata keys
+// are sorted so the output is stable.
+func WriteText(w io.Writer, puzzle *Puzzle) error {
+	var b strings.Builder
+	keys := make([]string, 0, len(puzzle.Metadata))
+	for key := range puzzle.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", key, puzzle.Metadata[key])
+	}
+
+	grid := puzzle.State.ToList()
+	spaced := false
+	for _, row := range grid {
+		for _, value := range row {
+			if value > 9 {
+				spaced = true
+			}
+		}
+	}
+	walls := wallSet(puzzle.Walls)
+	for x, row := range grid {
+		var cells, below []string
+		hasBelow := false
+		for y, value := range row {
+			cell := "."
+			if value != 0 {
+				cell = strconv.Itoa(value)
+			}
+			if y > 0 && walls[Wall{solver.Cell{X: x, Y: y - 1}, solver.Cell{X: x, Y: y}}] {
+				cell = "|" + cell
+			}
+			cells = append(cells, cell)
+			mark := "."
+			if walls[Wall{solver.Cell{X: x, Y: y}, solver.Cell{X: x + 1, Y: y}}] {
+				mark, hasBelow = "-", true
+			}
+			below = append(below, mark)
+		}
+		separator := ""
+		if spaced {
+			separator = " "
+		}
+		b.WriteString(strings.Join(cells, separator) + "\n")
+		if hasBelow {
+			b.WriteString(strings.Join(below, separator) + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func wallSet(walls []Wall) map[Wall]bool {
+	set := make(map[Wall]bool, len(walls))
+	for _, wall := range walls {
+		a, b := wall.A, wall.B
+		if b.X < a.X || b.X == a.X && b.Y < a.Y {
+			a, b = b, a
+		}
+		set[Wall{a, b}] = true
+	}
+	return set
+}
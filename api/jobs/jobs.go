@@ -0,0 +1,385 @@
+// Package jobs runs slow work such as puzzle generation and solving in the
+// background. Work is queued, picked up by a fixed number of workers and
+// given a time budget; its state can be polled by job ID, and is written to
+// a Store on every status change when one is configured.
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/batch"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// transitions lists the statuses a job may move to from each status. The
+// missing ones are final.
+var transitions = map[Status][]Status{
+	StatusQueued:  {StatusRunning, StatusCancelled},
+	StatusRunning: {StatusSucceeded, StatusFailed, StatusCancelled},
+}
+
+// CanMove reports whether a job may go from s to next.
+func (s Status) CanMove(next Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Finished reports whether s is a final status.
+func (s Status) Finished() bool {
+	return len(transitions[s]) == 0
+}
+
+// order ranks s along the way a job moves, queued first and final last.
+func (s Status) order() int {
+	switch {
+	case s == StatusQueued:
+		return 0
+	case s == StatusRunning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Progress is what a running task reports: the share of its work done, or
+// for solving, the search nodes explored and the current search depth.
+type Progress struct {
+	Fraction float64 `json:"progress"`
+	Nodes    int     `json:"nodes,omitempty"`
+	Depth    int     `json:"depth,omitempty"`
+}
+
+// Output is the result of a task.
+type Output struct {
+	MatrixID  uint64        `json:"matrix_id,omitempty"`
+	MatrixIDs []uint64      `json:"matrix_ids,omitempty"`
+	Solution  [][]int       `json:"solution,omitempty"`
+	Report    *batch.Report `json:"report,omitempty"`
+}
+
+// Job is the state of one unit of background work as reported to clients.
+type Job struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	UserID uint32 `json:"user_id"`
+	Status Status `json:"status"`
+	Progress
+	Output
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Task is the work of a job. It stops when cancel is closed and reports
+// its progress as it goes. A task that stops early may return what it
+// finished along with its error; the job keeps it.
+type Task func(cancel <-chan struct{}, report func(Progress)) (Output, error)
+
+// Store keeps job records beyond the life of the queue. Progress is not
+// stored, only the state after each status change.
+type Store interface {
+	SaveJob(job Job) error
+	FindJob(id string) (Job, error)
+}
+
+var (
+	// ErrQueueFull is returned by Submit when no more jobs can wait.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrBudgetExceeded is the error of a job that ran out of time.
+	ErrBudgetExceeded = errors.New("job ran out of time")
+	// ErrNotFound is returned for unknown job IDs.
+	ErrNotFound = errors.New("job not found")
+	// ErrFinished is returned by Cancel for jobs that already ended.
+	ErrFinished = errors.New("job already finished")
+)
+
+// Options configures a Queue. Zero values pick the defaults.
+type Options struct {
+	// Workers is the number of jobs run at once.
+	Workers int
+	// Capacity is the number of jobs that may wait.
+	Capacity int
+	// Budget is the time a job may run before it is cancelled.
+	Budget time.Duration
+	// Retention is how long finished jobs are kept in memory. The store
+	// keeps them for good.
+	Retention time.Duration
+	// Store, when set, records every status change.
+	Store Store
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers < 1 {
+		o.Workers = 2
+	}
+	if o.Capacity < 1 {
+		o.Capacity = 100
+	}
+	if o.Budget <= 0 {
+		o.Budget = 2 * time.Minute
+	}
+	if o.Retention <= 0 {
+		o.Retention = time.Hour
+	}
+	return o
+}
+
+// entry is a job together with what its worker needs.
+type entry struct {
+	job    Job
+	task   Task
+	cancel chan struct{}
+	stop   sync.Once
+	// cause is the status a stopped job ends in: cancelled when stopped by
+	// a user, failed when out of budget.
+	cause Status
+	// saving holds the store writes of the job in order; saved is the
+	// status last written, so a late write of an earlier one is dropped.
+	saving sync.Mutex
+	saved  Status
+}
+
+// Queue holds the jobs and runs them on its workers.
+type Queue struct {
+	options Options
+	pending chan *entry
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewQueue starts the workers of a new queue.
+func NewQueue(options Options) *Queue {
+	options = options.withDefaults()
+	q := &Queue{
+		options: options,
+		pending: make(chan *entry, options.Capacity),
+		entries: make(map[string]*entry),
+	}
+	for i := 0; i < options.Workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Submit queues a task and returns its job.
+func (q *Queue) Submit(kind string, userID uint32, task Task) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	e := &entry{
+		job:    Job{ID: id, Kind: kind, UserID: userID, Status: StatusQueued, CreatedAt: time.Now()},
+		task:   task,
+		cancel: make(chan struct{}),
+	}
+
+	q.mu.Lock()
+	q.prune()
+	select {
+	case q.pending <- e:
+	default:
+		q.mu.Unlock()
+		return Job{}, ErrQueueFull
+	}
+	q.entries[id] = e
+	job := e.job
+	q.mu.Unlock()
+
+	q.save(e, job)
+	return job, nil
+}
+
+// Get returns a snapshot of a job, falling back to the store for jobs no
+// longer held in memory.
+func (q *Queue) Get(id string) (Job, error) {
+	q.mu.Lock()
+	e, ok := q.entries[id]
+	var job Job
+	if ok {
+		job = e.job
+	}
+	q.mu.Unlock()
+	if ok {
+		return job, nil
+	}
+	if q.options.Store == nil {
+		return Job{}, ErrNotFound
+	}
+	return q.options.Store.FindJob(id)
+}
+
+// Cancel stops a job. A queued job is cancelled right away; a running one
+// once its task notices.
+func (q *Queue) Cancel(id string) error {
+	q.mu.Lock()
+	e, ok := q.entries[id]
+	if !ok {
+		q.mu.Unlock()
+		job, err := q.Get(id)
+		if err == nil && job.Status.Finished() {
+			return ErrFinished
+		}
+		return ErrNotFound
+	}
+	var job Job
+	var changed bool
+	switch e.job.Status {
+	case StatusQueued:
+		job, changed = q.move(e, StatusCancelled, nil)
+	case StatusRunning:
+		e.cause = StatusCancelled
+	default:
+		q.mu.Unlock()
+		return ErrFinished
+	}
+	q.mu.Unlock()
+
+	e.stop.Do(func() { close(e.cancel) })
+	if changed {
+		q.save(e, job)
+	}
+	return nil
+}
+
+// move changes the status of a job if the transition is allowed, applying
+// change as well, and returns the new state. The caller holds mu.
+func (q *Queue) move(e *entry, next Status, change func(job *Job)) (Job, bool) {
+	if !e.job.Status.CanMove(next) {
+		return e.job, false
+	}
+	now := time.Now()
+	e.job.Status = next
+	if next == StatusRunning {
+		e.job.StartedAt = &now
+	}
+	if next.Finished() {
+		e.job.FinishedAt = &now
+	}
+	if change != nil {
+		change(&e.job)
+	}
+	return e.job, true
+}
+
+// save writes job to the store unless a later status of it is already
+// there. Submit, Cancel and the worker save after letting go of mu, so
+// their writes may arrive in any order.
+func (q *Queue) save(e *entry, job Job) {
+	if q.options.Store == nil {
+		return
+	}
+	e.saving.Lock()
+	defer e.saving.Unlock()
+	if e.saved != "" && job.Status.order() <= e.saved.order() {
+		return
+	}
+	if err := q.options.Store.SaveJob(job); err != nil {
+		log.Printf("saving job %s: %v", job.ID, err)
+		return
+	}
+	e.saved = job.Status
+}
+
+// prune drops finished jobs older than the retention. The caller holds mu.
+func (q *Queue) prune() {
+	for id, e := range q.entries {
+		if e.job.Status.Finished() && time.Since(*e.job.FinishedAt) > q.options.Retention {
+			delete(q.entries, id)
+		}
+	}
+}
+
+func (q *Queue) work() {
+	for e := range q.pending {
+		q.run(e)
+	}
+}
+
+func (q *Queue) run(e *entry) {
+	q.mu.Lock()
+	job, ok := q.move(e, StatusRunning, nil)
+	q.mu.Unlock()
+	if !ok {
+		// Cancelled while it waited.
+		return
+	}
+	q.save(e, job)
+
+	timer := time.AfterFunc(q.options.Budget, func() {
+		q.mu.Lock()
+		if e.cause == "" {
+			e.cause = StatusFailed
+		}
+		q.mu.Unlock()
+		e.stop.Do(func() { close(e.cancel) })
+	})
+	report := func(progress Progress) {
+		q.mu.Lock()
+		e.job.Progress = progress
+		q.mu.Unlock()
+	}
+	output, err := runTask(e.task, e.cancel, report)
+	timer.Stop()
+
+	q.mu.Lock()
+	switch {
+	case err == nil:
+		job, _ = q.move(e, StatusSucceeded, func(job *Job) {
+			job.Progress.Fraction = 1
+			job.Output = output
+		})
+	case e.cause == StatusCancelled:
+		job, _ = q.move(e, StatusCancelled, func(job *Job) { job.Output = output })
+	default:
+		if e.cause == StatusFailed {
+			err = ErrBudgetExceeded
+		}
+		job, _ = q.move(e, StatusFailed, func(job *Job) {
+			job.Output = output
+			job.Error = err.Error()
+		})
+	}
+	q.mu.Unlock()
+	q.save(e, job)
+}
+
+// runTask runs a task, turning a panic into an error so a worker survives
+// a bad job.
+func runTask(task Task, cancel <-chan struct{}, report func(Progress)) (output Output, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprint(r))
+		}
+	}()
+	return task(cancel, report)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,207 @@
+// Package live solves a puzzle for a watching client, streaming every move
+// the search tries and takes back so the backtracking can be drawn as it
+// happens.
+package live
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// Conn is the client connection, a *websocket.Conn in the API.
+type Conn interface {
+	WriteText(data []byte) error
+	ReadMessage() ([]byte, error)
+}
+
+// Options controls a live solve. Zero values pick the defaults.
+type Options struct {
+	// Rate is the number of moves sent per second. The search waits for
+	// the client instead of skipping moves.
+	Rate int
+	// Timeout bounds the whole solve.
+	Timeout time.Duration
+	// Solve picks the search heuristics. Workers, Cancel and Trace are
+	// set by Run, and ModeExactCover, which cannot be traced, falls back
+	// to ModeCells.
+	Solve solver.SolveOptions
+}
+
+// MaxRate caps Options.Rate.
+const MaxRate = 1000
+
+const (
+	defaultRate    = 50
+	defaultTimeout = 10 * time.Minute
+)
+
+// Types of the messages sent to the client. A solve sends any number of
+// assign and undo messages followed by exactly one of the others.
+const (
+	TypeAssign     = "assign"
+	TypeUndo       = "undo"
+	TypeSolved     = "solved"
+	TypeUnsolvable = "unsolvable"
+	TypeStopped    = "stopped"
+	TypeTimeout    = "timeout"
+)
+
+// Move is an assign or undo message.
+type Move struct {
+	Type  string `json:"type"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Value int    `json:"value"`
+	Depth int    `json:"depth"`
+}
+
+// End is the last message of a solve.
+type End struct {
+	Type  string  `json:"type"`
+	Grid  [][]int `json:"grid,omitempty"`
+	Moves int     `json:"moves"`
+	Error string  `json:"error,omitempty"`
+}
+
+// stopMessage is what a client sends to stop the solve; closing the
+// connection works as well.
+var stopMessage = []byte("stop")
+
+// DefaultSlots is the number of live solves NewSlots lets run at once when
+// given none.
+const DefaultSlots = 4
+
+// Slots bounds the live solves running at once, as each one holds a search
+// and a connection for up to its timeout.
+type Slots chan struct{}
+
+// NewSlots returns room for n live solves at once.
+func NewSlots(n int) Slots {
+	if n < 1 {
+		n = DefaultSlots
+	}
+	return make(Slots, n)
+}
+
+// Take claims a slot without waiting and reports whether one was free.
+func (s Slots) Take() bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees a slot claimed with Take.
+func (s Slots) Release() {
+	<-s
+}
+
+// Run solves state while streaming its moves to conn, and returns once the
+// end message is sent. The caller closes the connection afterwards.
+func Run(conn Conn, state *solver.FieldState, options Options) error {
+	if options.Rate <= 0 {
+		options.Rate = defaultRate
+	}
+	if options.Rate > MaxRate {
+		options.Rate = MaxRate
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	var reason string
+	halt := func(why string) {
+		once.Do(func() {
+			reason = why
+			close(stop)
+		})
+	}
+	go func() {
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				halt(TypeStopped)
+				return
+			}
+			if isStop(message) {
+				halt(TypeStopped)
+			}
+		}
+	}()
+	timer := time.AfterFunc(options.Timeout, func() { halt(TypeTimeout) })
+	defer timer.Stop()
+
+	ticker := time.NewTicker(time.Second / time.Duration(options.Rate))
+	defer ticker.Stop()
+	var moves int
+	var writeErr error
+	solve := options.Solve
+	solve.Workers = 0
+	solve.Cancel = stop
+	solve.Trace = func(event solver.TraceEvent) {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		moves++
+		writeErr = writeJSON(conn, Move{
+			Type:  event.Kind.String(),
+			X:     event.Cell.X,
+			Y:     event.Cell.Y,
+			Value: event.Value,
+			Depth: event.Depth,
+		})
+		if writeErr != nil {
+			halt(TypeStopped)
+		}
+	}
+	if solve.Mode == solver.ModeExactCover {
+		solve.Mode = solver.ModeCells
+	}
+
+	ps := solver.NewPuzzleSolver(state)
+	ps.SetOptions(solve)
+	result, err := ps.Solve()
+	if writeErr != nil {
+		return writeErr
+	}
+	end := End{Type: TypeSolved, Moves: moves}
+	switch {
+	case err == solver.ErrCancelled:
+		end.Type = reason
+	case err != nil:
+		end.Type = TypeUnsolvable
+		end.Error = err.Error()
+	default:
+		end.Grid = result["solved_puzzle"].([][]int)
+	}
+	return writeJSON(conn, end)
+}
+
+func isStop(message []byte) bool {
+	message = bytes.TrimSpace(message)
+	if bytes.EqualFold(message, stopMessage) {
+		return true
+	}
+	request := struct {
+		Type string `json:"type"`
+	}{}
+	return json.Unmarshal(message, &request) == nil && request.Type == string(stopMessage)
+}
+
+func writeJSON(conn Conn, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(data)
+}
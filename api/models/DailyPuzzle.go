@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/daily"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// DailyPuzzle is the puzzle of the day for one date.
+type DailyPuzzle struct {
+	Date        string    `gorm:"primary_key;size:10" json:"date"`
+	Size        int       `gorm:"not null" json:"size"`
+	Difficulty  string    `gorm:"size:16;not null" json:"difficulty"`
+	Grid        Grid      `gorm:"type:text;not null" json:"grid"`
+	Fingerprint string    `gorm:"size:64;index" json:"fingerprint"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DailyStore keeps the puzzles of a daily.Scheduler in the database.
+type DailyStore struct {
+	DB *gorm.DB
+}
+
+func (s DailyStore) FindDaily(date string) (daily.Puzzle, error) {
+	d := DailyPuzzle{}
+	err := s.DB.Debug().Model(&DailyPuzzle{}).Where("date = ?", date).Take(&d).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return daily.Puzzle{}, daily.ErrNotFound
+	}
+	if err != nil {
+		return daily.Puzzle{}, err
+	}
+	return daily.Puzzle{Date: d.Date, Size: d.Size, Difficulty: d.Difficulty, Grid: d.Grid}, nil
+}
+
+// SaveDaily inserts the puzzle of a date. When another server got there
+// first, the date's primary key rejects the insert and its puzzle is
+// returned instead.
+func (s DailyStore) SaveDaily(puzzle daily.Puzzle) (daily.Puzzle, error) {
+	d := DailyPuzzle{Date: puzzle.Date, Size: puzzle.Size, Difficulty: puzzle.Difficulty, Grid: copyGrid(puzzle.Grid)}
+	result, err := solver.FromListToState(puzzle.Grid)
+	if err != nil {
+		return daily.Puzzle{}, err
+	}
+	d.Fingerprint = result["state"].(*solver.FieldState).Fingerprint()
+	err = s.DB.Debug().Create(&d).Error
+	if err != nil {
+		if existing, findErr := s.FindDaily(puzzle.Date); findErr == nil {
+			return existing, nil
+		}
+		return daily.Puzzle{}, err
+	}
+	return puzzle, nil
+}
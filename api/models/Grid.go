@@ -0,0 +1,49 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Grid is a board stored in a text column as a JSON array of rows. A nil
+// grid is stored as NULL.
+type Grid [][]int
+
+func (g Grid) Value() (driver.Value, error) {
+	if g == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal([][]int(g))
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+func (g *Grid) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*g = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[][]int)(g))
+	case string:
+		return json.Unmarshal([]byte(v), (*[][]int)(g))
+	default:
+		return fmt.Errorf("cannot read a grid from %T", src)
+	}
+}
+
+// copyGrid returns a deep copy of a grid, so callers cannot change a
+// stored one through a returned slice.
+func copyGrid(grid [][]int) [][]int {
+	if grid == nil {
+		return nil
+	}
+	copied := make([][]int, len(grid))
+	for x, row := range grid {
+		copied[x] = append([]int(nil), row...)
+	}
+	return copied
+}
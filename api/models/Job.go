@@ -0,0 +1,130 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
+)
+
+// Job is the stored record of a background job: its status, and its result
+// once finished.
+type Job struct {
+	ID         string     `gorm:"primary_key;size:16" json:"id"`
+	Kind       string     `gorm:"size:32;not null" json:"kind"`
+	UserID     uint32     `json:"user_id"`
+	Status     string     `gorm:"size:16;not null;index" json:"status"`
+	MatrixID   uint64     `json:"matrix_id"`
+	MatrixIDs  string     `gorm:"type:text" json:"matrix_ids"`
+	Solution   string     `gorm:"type:text" json:"solution"`
+	Report     string     `gorm:"type:text" json:"report"`
+	Error      string     `gorm:"type:text" json:"error"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func newJob(job jobs.Job) (*Job, error) {
+	j := &Job{
+		ID:         job.ID,
+		Kind:       job.Kind,
+		UserID:     job.UserID,
+		Status:     string(job.Status),
+		MatrixID:   job.MatrixID,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.Solution != nil {
+		solution, err := json.Marshal(job.Solution)
+		if err != nil {
+			return nil, err
+		}
+		j.Solution = string(solution)
+	}
+	if job.MatrixIDs != nil {
+		ids, err := json.Marshal(job.MatrixIDs)
+		if err != nil {
+			return nil, err
+		}
+		j.MatrixIDs = string(ids)
+	}
+	if job.Report != nil {
+		report, err := json.Marshal(job.Report)
+		if err != nil {
+			return nil, err
+		}
+		j.Report = string(report)
+	}
+	return j, nil
+}
+
+func (j *Job) toJob() (jobs.Job, error) {
+	job := jobs.Job{
+		ID:         j.ID,
+		Kind:       j.Kind,
+		UserID:     j.UserID,
+		Status:     jobs.Status(j.Status),
+		Output:     jobs.Output{MatrixID: j.MatrixID},
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+	}
+	if job.Status == jobs.StatusSucceeded {
+		job.Fraction = 1
+	}
+	if j.Solution != "" {
+		if err := json.Unmarshal([]byte(j.Solution), &job.Solution); err != nil {
+			return jobs.Job{}, err
+		}
+	}
+	if j.MatrixIDs != "" {
+		if err := json.Unmarshal([]byte(j.MatrixIDs), &job.MatrixIDs); err != nil {
+			return jobs.Job{}, err
+		}
+	}
+	if j.Report != "" {
+		if err := json.Unmarshal([]byte(j.Report), &job.Report); err != nil {
+			return jobs.Job{}, err
+		}
+	}
+	return job, nil
+}
+
+// JobStore keeps the records of a jobs.Queue in the database.
+type JobStore struct {
+	DB *gorm.DB
+}
+
+func (s JobStore) SaveJob(job jobs.Job) error {
+	j, err := newJob(job)
+	if err != nil {
+		return err
+	}
+	return s.DB.Debug().Save(j).Error
+}
+
+func (s JobStore) FindJob(id string) (jobs.Job, error) {
+	j := Job{}
+	err := s.DB.Debug().Model(&Job{}).Where("id = ?", id).Take(&j).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return jobs.Job{}, jobs.ErrNotFound
+	}
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	return j.toJob()
+}
+
+// FailUnfinishedJobs marks the jobs a previous run of the server left
+// queued or running as failed, as nothing will pick them up again.
+func FailUnfinishedJobs(db *gorm.DB) error {
+	return db.Debug().Model(&Job{}).
+		Where("status IN (?)", []string{string(jobs.StatusQueued), string(jobs.StatusRunning)}).
+		Updates(map[string]interface{}{"status": string(jobs.StatusFailed), "error": "interrupted by a server restart", "finished_at": time.Now()}).Error
+}
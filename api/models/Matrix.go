@@ -1,29 +1,98 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+
 	"github.com/jinzhu/gorm"
-	"github.com/lib/pq" // for PostgreSQL JSONB
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
 )
 
+// ErrDuplicateMatrix is returned by SaveMatrix when the user already stored
+// a rotation or mirror image of the puzzle.
+var ErrDuplicateMatrix = errors.New("Matrix Already Exists")
+
+// ErrNoSolution is returned by SolutionGrid for matrices stored without
+// their solution.
+var ErrNoSolution = errors.New("Matrix Has No Solution")
+
+// VariantFillomino is the only variant of puzzle the solver handles, and
+// the one matrices are stored as unless told otherwise.
+const VariantFillomino = "fillomino"
+
+// Matrix is a stored puzzle: its clues, with 0 for an empty cell, and its
+// solution and difficulty once known.
 type Matrix struct {
-	ID          uint64         `gorm:"primary_key;auto_increment" json:"id"`
-	Coordinates pq.Int32Array  `gorm:"type:jsonb;not null" json:"coordinates"`
-	UserID      uint32         `sql:"type:int REFERENCES users(id)" json:"user_id"`
-	User        User           `json:"user"`
-	CreatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID          uint64      `gorm:"primary_key;auto_increment" json:"id"`
+	Width       int         `gorm:"not null;default:0" json:"width"`
+	Height      int         `gorm:"not null;default:0" json:"height"`
+	Clues       Grid        `gorm:"type:text" json:"clues"`
+	Solution    Grid        `gorm:"type:text" json:"solution"`
+	Variant     string      `gorm:"size:32;not null;default:'fillomino'" json:"variant"`
+	Difficulty  string      `gorm:"size:16;index" json:"difficulty"`
+	Fingerprint Fingerprint `gorm:"size:64;index" json:"fingerprint"`
+	Source      string      `gorm:"size:255" json:"source"`
+	Metadata    string      `gorm:"type:text" json:"metadata"`
+	UserID      uint32      `sql:"type:int REFERENCES users(id)" json:"user_id"`
+	User        User        `json:"user"`
+	CreatedAt   time.Time   `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time   `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// Fingerprint identifies a puzzle up to rotation and mirroring. Boards
+// without one, such as rectangular ones, store NULL, so they are not held
+// to the unique index on the user and fingerprint.
+type Fingerprint string
+
+func (f Fingerprint) Value() (driver.Value, error) {
+	if f == "" {
+		return nil, nil
+	}
+	return string(f), nil
+}
+
+func (f *Fingerprint) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*f = ""
+	case []byte:
+		*f = Fingerprint(v)
+	case string:
+		*f = Fingerprint(v)
+	default:
+		return fmt.Errorf("cannot read a fingerprint from %T", src)
+	}
+	return nil
 }
 
 func (m *Matrix) Prepare() {
 	m.ID = 0
+	if m.Variant == "" {
+		m.Variant = VariantFillomino
+	}
 	m.CreatedAt = time.Now()
 	m.UpdatedAt = time.Now()
 }
 
 func (m *Matrix) Validate() error {
-	if len(m.Coordinates) == 0 {
-		return errors.New("Required Coordinates")
+	if len(m.Clues) == 0 {
+		return errors.New("Required Clues")
+	}
+	if err := checkGrid(m.Clues, m.Width, m.Height); err != nil {
+		return err
+	}
+	if m.Solution != nil {
+		if err := checkGrid(m.Solution, m.Width, m.Height); err != nil {
+			return err
+		}
+	}
+	if m.Variant != VariantFillomino {
+		return errors.New("Unknown Variant")
 	}
 	if m.UserID < 1 {
 		return errors.New("Required UserID")
@@ -31,9 +100,156 @@ func (m *Matrix) Validate() error {
 	return nil
 }
 
+// checkGrid reports whether a grid has the given dimensions.
+func checkGrid(grid [][]int, width, height int) error {
+	if len(grid) != height {
+		return fmt.Errorf("Grid Has %d Rows, Expected %d", len(grid), height)
+	}
+	for x, row := range grid {
+		if len(row) != width {
+			return fmt.Errorf("Row %d Has %d Cells, Expected %d", x, len(row), width)
+		}
+	}
+	return nil
+}
+
+// SetClues stores the clue grid of the puzzle with its dimensions and
+// fingerprint. A solution and difficulty set before belong to other clues
+// and are cleared.
+func (m *Matrix) SetClues(grid [][]int) error {
+	if len(grid) == 0 || len(grid[0]) == 0 {
+		return errors.New("Required Clues")
+	}
+	if err := checkGrid(grid, len(grid[0]), len(grid)); err != nil {
+		return err
+	}
+	m.Height, m.Width = len(grid), len(grid[0])
+	m.Clues = copyGrid(grid)
+	m.Solution = nil
+	m.Difficulty = ""
+	m.Fingerprint = ""
+	result, err := solver.FromListToState(grid)
+	if err == nil {
+		m.Fingerprint = Fingerprint(result["state"].(*solver.FieldState).Fingerprint())
+	}
+	return nil
+}
+
+// SetSolution stores the solution of the puzzle, which must fill every
+// cell and agree with the clues.
+func (m *Matrix) SetSolution(grid [][]int) error {
+	if err := checkGrid(grid, m.Width, m.Height); err != nil {
+		return err
+	}
+	for x, row := range grid {
+		for y, value := range row {
+			if value < 1 {
+				return fmt.Errorf("Solution Is Empty At %d,%d", x, y)
+			}
+			if clue := m.Clues[x][y]; clue != 0 && clue != value {
+				return fmt.Errorf("Solution Does Not Match The Clue At %d,%d", x, y)
+			}
+		}
+	}
+	m.Solution = copyGrid(grid)
+	return nil
+}
+
+// Grid returns the clue grid.
+func (m *Matrix) Grid() ([][]int, error) {
+	if len(m.Clues) == 0 {
+		return nil, errors.New("Required Clues")
+	}
+	if err := checkGrid(m.Clues, m.Width, m.Height); err != nil {
+		return nil, err
+	}
+	return copyGrid(m.Clues), nil
+}
+
+// SolutionGrid returns the stored solution.
+func (m *Matrix) SolutionGrid() ([][]int, error) {
+	if m.Solution == nil {
+		return nil, ErrNoSolution
+	}
+	if err := checkGrid(m.Solution, m.Width, m.Height); err != nil {
+		return nil, err
+	}
+	return copyGrid(m.Solution), nil
+}
+
+// Rate solves the clues and stores the solution and difficulty. Puzzles
+// without a unique solution keep neither. Closing cancel stops the search
+// with solver.ErrCancelled.
+func (m *Matrix) Rate(cancel <-chan struct{}) error {
+	grid, err := m.Grid()
+	if err != nil {
+		return err
+	}
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		return err
+	}
+	ps := solver.NewPuzzleSolver(result["state"].(*solver.FieldState))
+	ps.SetOptions(solver.SolveOptions{Cancel: cancel})
+	rating, err := ps.Rate()
+	if err != nil {
+		return err
+	}
+	m.Solution, m.Difficulty = nil, ""
+	if rating.Solutions != 1 {
+		return nil
+	}
+	result, err = solver.FromListToState(grid)
+	if err != nil {
+		return err
+	}
+	ps = solver.NewPuzzleSolver(result["state"].(*solver.FieldState))
+	ps.SetOptions(solver.SolveOptions{Cancel: cancel})
+	solved, err := ps.Solve()
+	if err != nil {
+		return err
+	}
+	if err := m.SetSolution(solved["solved_puzzle"].([][]int)); err != nil {
+		return err
+	}
+	m.Difficulty = rating.Difficulty
+	return nil
+}
+
+// SetMetadata stores the metadata of an imported puzzle as JSON.
+func (m *Matrix) SetMetadata(metadata map[string]string) error {
+	if len(metadata) == 0 {
+		m.Metadata = ""
+		return nil
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	m.Metadata = string(encoded)
+	return nil
+}
+
+// SaveMatrix stores the matrix unless its user already stored one with the
+// same fingerprint, in which case that one is returned with
+// ErrDuplicateMatrix. Other users may store the same puzzle. The unique
+// index on the user and fingerprint decides, so two requests saving the
+// same puzzle at once cannot both store it.
 func (m *Matrix) SaveMatrix(db *gorm.DB) (*Matrix, error) {
 	var err error
 	err = db.Debug().Model(&Matrix{}).Create(&m).Error
+	if err != nil && m.Fingerprint != "" && isDuplicateFingerprint(err) {
+		existing := Matrix{}
+		err = db.Debug().Model(&Matrix{}).Where("user_id = ? AND fingerprint = ?", m.UserID, m.Fingerprint).Take(&existing).Error
+		if err != nil {
+			return &Matrix{}, err
+		}
+		err = db.Debug().Model(&User{}).Where("id = ?", existing.UserID).Take(&existing.User).Error
+		if err != nil {
+			return &Matrix{}, err
+		}
+		return &existing, ErrDuplicateMatrix
+	}
 	if err != nil {
 		return &Matrix{}, err
 	}
@@ -46,6 +262,16 @@ func (m *Matrix) SaveMatrix(db *gorm.DB) (*Matrix, error) {
 	return m, nil
 }
 
+// uniqueFingerprintIndex holds each user to one matrix per fingerprint.
+const uniqueFingerprintIndex = "uix_matrices_user_fingerprint"
+
+// isDuplicateFingerprint reports whether err is a violation of the unique
+// index on the user and fingerprint, as worded by PostgreSQL or SQLite.
+func isDuplicateFingerprint(err error) bool {
+	return strings.Contains(err.Error(), uniqueFingerprintIndex) ||
+		strings.Contains(err.Error(), "matrices.user_id, matrices.fingerprint")
+}
+
 func (m *Matrix) FindAllMatrices(db *gorm.DB) (*[]Matrix, error) {
 	var err error
 	matrices := []Matrix{}
@@ -78,3 +304,18 @@ func (m *Matrix) FindMatrixByID(db *gorm.DB, mid uint64) (*Matrix, error) {
 	}
 	return m, nil
 }
+
+// FindMatrixByFingerprint finds the matrix of user uid that is the same
+// puzzle as the fingerprint up to rotation and mirroring.
+func (m *Matrix) FindMatrixByFingerprint(db *gorm.DB, uid uint32, fingerprint string) (*Matrix, error) {
+	found := Matrix{}
+	err := db.Debug().Model(&Matrix{}).Where("user_id = ? AND fingerprint = ?", uid, fingerprint).Take(&found).Error
+	if err != nil {
+		return &Matrix{}, err
+	}
+	err = db.Debug().Model(&User{}).Where("id = ?", found.UserID).Take(&found.User).Error
+	if err != nil {
+		return &Matrix{}, err
+	}
+	return &found, nil
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/pool"
+)
+
+// PoolPuzzle is a generated puzzle waiting in the pool to be handed out,
+// with the solution found when it was generated. Rows stocked before the
+// solution was kept have none.
+type PoolPuzzle struct {
+	ID         uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	Size       int       `gorm:"not null;index:idx_pool_bucket" json:"size"`
+	Difficulty string    `gorm:"size:16;not null;index:idx_pool_bucket" json:"difficulty"`
+	Grid       Grid      `gorm:"type:text;not null" json:"grid"`
+	Solution   Grid      `gorm:"type:text" json:"solution"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PoolStore keeps the puzzles of a pool.Pool in the database.
+type PoolStore struct {
+	DB *gorm.DB
+}
+
+func (s PoolStore) CountPuzzles(bucket pool.Bucket) (int, error) {
+	count := 0
+	err := s.DB.Debug().Model(&PoolPuzzle{}).Where("size = ? AND difficulty = ?", bucket.Size, bucket.Difficulty).Count(&count).Error
+	return count, err
+}
+
+func (s PoolStore) AddPuzzle(bucket pool.Bucket, puzzle pool.Puzzle) error {
+	p := PoolPuzzle{Size: bucket.Size, Difficulty: puzzle.Difficulty, Grid: copyGrid(puzzle.Clues), Solution: copyGrid(puzzle.Solution)}
+	return s.DB.Debug().Create(&p).Error
+}
+
+// TakePuzzle hands out the oldest puzzle of the bucket. A puzzle deleted
+// by a concurrent taker in between is skipped.
+func (s PoolStore) TakePuzzle(bucket pool.Bucket) (pool.Puzzle, error) {
+	for {
+		p := PoolPuzzle{}
+		err := s.DB.Debug().Model(&PoolPuzzle{}).Where("size = ? AND difficulty = ?", bucket.Size, bucket.Difficulty).Order("id").Take(&p).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return pool.Puzzle{}, pool.ErrEmpty
+		}
+		if err != nil {
+			return pool.Puzzle{}, err
+		}
+		deleted := s.DB.Debug().Where("id = ?", p.ID).Delete(&PoolPuzzle{})
+		if deleted.Error != nil {
+			return pool.Puzzle{}, deleted.Error
+		}
+		if deleted.RowsAffected == 0 {
+			continue
+		}
+		return pool.Puzzle{Clues: p.Grid, Solution: p.Solution, Difficulty: p.Difficulty}, nil
+	}
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SchemaMigration records a migration that has run.
+type SchemaMigration struct {
+	ID        string    `gorm:"primary_key;size:64" json:"id"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// migration changes stored data in a way AutoMigrate cannot.
+type migration struct {
+	id  string
+	run func(tx *gorm.DB) error
+}
+
+// migrations run once each, in order. New ones go at the end; one that
+// has run somewhere is never edited.
+var migrations = []migration{
+	{"0001_matrix_grids", migrateMatrixGrids},
+	{"0002_unique_matrix_fingerprints", migrateUniqueFingerprints},
+}
+
+// Migrate runs the pending migrations, each in its own transaction, and
+// then brings the matrices table up to the current Matrix, including the
+// unique index on the user and fingerprint.
+func Migrate(db *gorm.DB) error {
+	err := db.Debug().AutoMigrate(&SchemaMigration{}).Error
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		applied := 0
+		err = db.Debug().Model(&SchemaMigration{}).Where("id = ?", m.id).Count(&applied).Error
+		if err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+		tx := db.Begin()
+		err = m.run(tx)
+		if err == nil {
+			err = tx.Create(&SchemaMigration{ID: m.id, AppliedAt: time.Now()}).Error
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %s: %v", m.id, err)
+		}
+		err = tx.Commit().Error
+		if err != nil {
+			return err
+		}
+	}
+	err = db.Debug().AutoMigrate(&Matrix{}).Error
+	if err != nil {
+		return err
+	}
+	return db.Debug().Model(&Matrix{}).AddUniqueIndex(uniqueFingerprintIndex, "user_id", "fingerprint").Error
+}
+
+// legacyMatrix is a row of the matrices table from before the clues and
+// solution had columns of their own. Coordinates held the clues as one
+// flat array of a square grid, written {1,2,...} or [1,2,...].
+type legacyMatrix struct {
+	ID          uint64
+	Coordinates string
+	UserID      uint32
+	Source      string
+	Metadata    string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// gridMatrix is a row of the matrices table as migrateMatrixGrids builds
+// it. It is kept apart from Matrix so the migration creates the same table
+// however Matrix changes later.
+type gridMatrix struct {
+	ID          uint64    `gorm:"primary_key;auto_increment"`
+	Width       int       `gorm:"not null;default:0"`
+	Height      int       `gorm:"not null;default:0"`
+	Clues       Grid      `gorm:"type:text"`
+	Solution    Grid      `gorm:"type:text"`
+	Variant     string    `gorm:"size:32;not null;default:'fillomino'"`
+	Difficulty  string    `gorm:"size:16;index"`
+	Fingerprint string    `gorm:"size:64;index"`
+	Source      string    `gorm:"size:255"`
+	Metadata    string    `gorm:"type:text"`
+	UserID      uint32    `sql:"type:int REFERENCES users(id)"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (gridMatrix) TableName() string {
+	return "matrices"
+}
+
+// migrateMatrixGrids moves the Coordinates column of the matrices table
+// into Width, Height and Clues. The table is rebuilt rather than altered,
+// as SQLite cannot drop a column.
+func migrateMatrixGrids(tx *gorm.DB) error {
+	if !tx.HasTable("matrices") || !tx.Dialect().HasColumn("matrices", "coordinates") {
+		return nil
+	}
+	steps := []string{
+		"ALTER TABLE matrices RENAME TO matrices_legacy",
+		// Index names are shared by the whole schema, so the old ones
+		// would clash with the new table's.
+		"DROP INDEX IF EXISTS idx_matrices_fingerprint",
+	}
+	for _, step := range steps {
+		if err := tx.Exec(step).Error; err != nil {
+			return err
+		}
+	}
+	err := tx.AutoMigrate(&gridMatrix{}).Error
+	if err != nil {
+		return err
+	}
+
+	legacy := []legacyMatrix{}
+	err = tx.Table("matrices_legacy").Find(&legacy).Error
+	if err != nil {
+		return err
+	}
+	for _, l := range legacy {
+		grid, err := parseCoordinates(l.Coordinates)
+		if err != nil {
+			return fmt.Errorf("matrix %d: %v", l.ID, err)
+		}
+		m := Matrix{}
+		if err := m.SetClues(grid); err != nil {
+			return fmt.Errorf("matrix %d: %v", l.ID, err)
+		}
+		row := gridMatrix{
+			ID:          l.ID,
+			Width:       m.Width,
+			Height:      m.Height,
+			Clues:       m.Clues,
+			Variant:     VariantFillomino,
+			Fingerprint: string(m.Fingerprint),
+			Source:      l.Source,
+			Metadata:    l.Metadata,
+			UserID:      l.UserID,
+			CreatedAt:   l.CreatedAt,
+			UpdatedAt:   l.UpdatedAt,
+		}
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	if tx.Dialect().GetName() == "postgres" && len(legacy) > 0 {
+		// Rows were copied with their ids, which the sequence did not hand
+		// out.
+		err = tx.Exec("SELECT setval(pg_get_serial_sequence('matrices', 'id'), MAX(id)) FROM matrices").Error
+		if err != nil {
+			return err
+		}
+	}
+	return tx.DropTable("matrices_legacy").Error
+}
+
+// migrateUniqueFingerprints clears the fingerprints that would break the
+// unique index on the user and fingerprint: empty ones become NULL and, of
+// a puzzle a user stored more than once, only their oldest row keeps it.
+// The other rows stay stored. Migrate creates the index afterwards.
+func migrateUniqueFingerprints(tx *gorm.DB) error {
+	if !tx.HasTable("matrices") || !tx.Dialect().HasColumn("matrices", "fingerprint") {
+		return nil
+	}
+	steps := []string{
+		"UPDATE matrices SET fingerprint = NULL WHERE fingerprint = ''",
+		"UPDATE matrices SET fingerprint = NULL WHERE id NOT IN (SELECT MIN(id) FROM matrices WHERE fingerprint IS NOT NULL GROUP BY user_id, fingerprint)",
+	}
+	for _, step := range steps {
+		if err := tx.Exec(step).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseCoordinates reads a flat array of a square grid.
+func parseCoordinates(coordinates string) ([][]int, error) {
+	fields := strings.Split(strings.Trim(strings.TrimSpace(coordinates), "{}[]"), ",")
+	size := int(math.Sqrt(float64(len(fields))))
+	if size == 0 || size*size != len(fields) {
+		return nil, fmt.Errorf("%d coordinates are not a square grid", len(fields))
+	}
+	grid := make([][]int, size)
+	for x := range grid {
+		grid[x] = make([]int, size)
+		for y := range grid[x] {
+			value, err := strconv.Atoi(strings.TrimSpace(fields[x*size+y]))
+			if err != nil {
+				return nil, err
+			}
+			grid[x][y] = value
+		}
+	}
+	return grid, nil
+}
@@ -0,0 +1,267 @@
+// Package pool keeps a stock of generated puzzles per size and difficulty,
+// so a new puzzle can be handed out without waiting for the generator. A
+// background worker tops every bucket up to its level.
+package pool
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// Bucket is the size and difficulty of the puzzles stocked together.
+type Bucket struct {
+	Size       int    `json:"size"`
+	Difficulty string `json:"difficulty"`
+}
+
+// Puzzle is a stocked puzzle: its clues with the solution and difficulty
+// found when it was generated, so it can be handed out without solving it
+// again.
+type Puzzle struct {
+	Clues      [][]int
+	Solution   [][]int
+	Difficulty string
+}
+
+// Stock is the number of puzzles held in a bucket.
+type Stock struct {
+	Bucket
+	Count int `json:"count"`
+	Level int `json:"level"`
+}
+
+// Store holds the stocked puzzles.
+type Store interface {
+	CountPuzzles(bucket Bucket) (int, error)
+	AddPuzzle(bucket Bucket, puzzle Puzzle) error
+	// TakePuzzle removes a puzzle of the bucket and returns it, or fails
+	// with ErrEmpty. A puzzle is never handed out twice.
+	TakePuzzle(bucket Bucket) (Puzzle, error)
+}
+
+var (
+	// ErrEmpty is returned by Take when the bucket is out of stock.
+	ErrEmpty = errors.New("no puzzle in stock")
+	// ErrUnknownBucket is returned by Take for buckets the pool does not
+	// stock.
+	ErrUnknownBucket = errors.New("puzzles of that size and difficulty are not stocked")
+)
+
+// Options configures a Pool. Zero values pick the defaults.
+type Options struct {
+	// Buckets are the sizes and difficulties stocked, by default every
+	// difficulty of DefaultSizes.
+	Buckets []Bucket
+	// Level is the number of puzzles the worker keeps in every bucket.
+	Level int
+	// Interval is the time between two refill rounds. Taking a puzzle
+	// starts a round right away.
+	Interval time.Duration
+	// Attempts bounds the solutions drawn for one puzzle, see
+	// solver.PuzzleGenerator.GenerateRated.
+	Attempts int
+	// Store keeps the puzzles, in memory unless set.
+	Store Store
+}
+
+// DefaultSizes are the board sizes stocked when Options.Buckets is empty.
+var DefaultSizes = []int{5, 6, 7}
+
+const (
+	defaultLevel    = 5
+	defaultInterval = time.Minute
+	defaultAttempts = 50
+)
+
+// Pool hands out stocked puzzles and refills them in the background.
+type Pool struct {
+	options Options
+	stocked map[Bucket]bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// New makes a pool. Its worker only runs once Start is called.
+func New(options Options) *Pool {
+	if len(options.Buckets) == 0 {
+		options.Buckets = Buckets(DefaultSizes)
+	}
+	if options.Level <= 0 {
+		options.Level = defaultLevel
+	}
+	if options.Interval <= 0 {
+		options.Interval = defaultInterval
+	}
+	if options.Attempts <= 0 {
+		options.Attempts = defaultAttempts
+	}
+	if options.Store == nil {
+		options.Store = NewMemoryStore()
+	}
+	p := &Pool{
+		options: options,
+		stocked: make(map[Bucket]bool),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, bucket := range options.Buckets {
+		p.stocked[bucket] = true
+	}
+	return p
+}
+
+// Buckets returns a bucket for every difficulty of every size.
+func Buckets(sizes []int) []Bucket {
+	var buckets []Bucket
+	for _, size := range sizes {
+		for _, difficulty := range solver.Difficulties {
+			buckets = append(buckets, Bucket{Size: size, Difficulty: difficulty})
+		}
+	}
+	return buckets
+}
+
+// Take removes a puzzle from its bucket and returns it, and has the worker
+// replace it.
+func (p *Pool) Take(bucket Bucket) (Puzzle, error) {
+	if !p.stocked[bucket] {
+		return Puzzle{}, ErrUnknownBucket
+	}
+	puzzle, err := p.options.Store.TakePuzzle(bucket)
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return puzzle, err
+}
+
+// Stock reports the number of puzzles in every bucket.
+func (p *Pool) Stock() ([]Stock, error) {
+	stock := make([]Stock, 0, len(p.options.Buckets))
+	for _, bucket := range p.options.Buckets {
+		count, err := p.options.Store.CountPuzzles(bucket)
+		if err != nil {
+			return nil, err
+		}
+		stock = append(stock, Stock{Bucket: bucket, Count: count, Level: p.options.Level})
+	}
+	return stock, nil
+}
+
+// Refill tops every bucket up to the level, until cancel is closed. A
+// bucket the generator cannot fill is skipped until the next round.
+func (p *Pool) Refill(cancel <-chan struct{}) error {
+	for _, bucket := range p.options.Buckets {
+		count, err := p.options.Store.CountPuzzles(bucket)
+		if err != nil {
+			return err
+		}
+		for ; count < p.options.Level; count++ {
+			generator := solver.NewPuzzleGenerator(bucket.Size)
+			generator.SetCancel(cancel)
+			grid, err := generator.GenerateRated(bucket.Difficulty, p.options.Attempts)
+			var solution [][]int
+			if err == nil {
+				solution, err = solve(grid, cancel)
+			}
+			if err == solver.ErrCancelled {
+				return err
+			}
+			if err != nil {
+				log.Printf("refilling %dx%d %s puzzles: %v", bucket.Size, bucket.Size, bucket.Difficulty, err)
+				break
+			}
+			puzzle := Puzzle{Clues: grid, Solution: solution, Difficulty: bucket.Difficulty}
+			if err := p.options.Store.AddPuzzle(bucket, puzzle); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// solve returns the solution of a generated puzzle.
+func solve(grid [][]int, cancel <-chan struct{}) ([][]int, error) {
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		return nil, err
+	}
+	ps := solver.NewPuzzleSolver(result["state"].(*solver.FieldState))
+	ps.SetOptions(solver.SolveOptions{Cancel: cancel})
+	solved, err := ps.Solve()
+	if err != nil {
+		return nil, err
+	}
+	return solved["solved_puzzle"].([][]int), nil
+}
+
+// Start runs the refill worker: a round right away, then one every
+// Interval or after a puzzle is taken.
+func (p *Pool) Start() {
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.options.Interval)
+		defer ticker.Stop()
+		for {
+			err := p.Refill(p.stop)
+			if err != nil && err != solver.ErrCancelled {
+				log.Printf("refilling the puzzle pool: %v", err)
+			}
+			select {
+			case <-p.stop:
+				return
+			case <-p.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the worker of a started pool, abandoning the puzzle it is
+// generating, and waits for it.
+func (p *Pool) Stop() {
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+// MemoryStore is a Store that keeps the puzzles in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	puzzles map[Bucket][]Puzzle
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{puzzles: make(map[Bucket][]Puzzle)}
+}
+
+func (s *MemoryStore) CountPuzzles(bucket Bucket) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.puzzles[bucket]), nil
+}
+
+func (s *MemoryStore) AddPuzzle(bucket Bucket, puzzle Puzzle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.puzzles[bucket] = append(s.puzzles[bucket], puzzle)
+	return nil
+}
+
+func (s *MemoryStore) TakePuzzle(bucket Bucket) (Puzzle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	puzzles := s.puzzles[bucket]
+	if len(puzzles) == 0 {
+		return Puzzle{}, ErrEmpty
+	}
+	s.puzzles[bucket] = puzzles[1:]
+	return puzzles[0], nil
+}
@@ -0,0 +1,141 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// Entry is one puzzle of a booklet. Entries without a solution are left
+// out of the answer pages.
+type Entry struct {
+	Puzzle   *solver.FieldState
+	Solution *solver.FieldState
+}
+
+// BookletOptions controls the booklet layout. Zero values pick the
+// defaults.
+type BookletOptions struct {
+	PerPage int
+	// DPI is the resolution of PNG pages.
+	DPI int
+}
+
+const (
+	defaultPerPage = 4
+	defaultDPI     = 150
+	pageMargin     = 40.0
+	labelHeight    = 24.0
+)
+
+// BookletPDF writes the puzzles as an A4 PDF, PerPage to a page and
+// numbered in order, followed by pages with the solutions.
+func BookletPDF(w io.Writer, entries []Entry, options BookletOptions) error {
+	pages, err := layoutBooklet(entries, options)
+	if err != nil {
+		return err
+	}
+	canvases := make([]*pdfCanvas, len(pages))
+	for i, page := range pages {
+		canvases[i] = &pdfCanvas{}
+		page.draw(canvases[i])
+	}
+	return writePDF(w, canvases)
+}
+
+// BookletPNG draws the same pages as BookletPDF as images.
+func BookletPNG(entries []Entry, options BookletOptions) ([]image.Image, error) {
+	pages, err := layoutBooklet(entries, options)
+	if err != nil {
+		return nil, err
+	}
+	dpi := options.DPI
+	if dpi <= 0 {
+		dpi = defaultDPI
+	}
+	scale := float64(dpi) / 72
+	images := make([]image.Image, len(pages))
+	for i, page := range pages {
+		r := newRaster(int(math.Ceil(pageWidth*scale)), int(math.Ceil(pageHeight*scale)), scale)
+		page.draw(r)
+		images[i] = r.img
+	}
+	return images, nil
+}
+
+// bookletPage is a page of numbered boards, placed in a grid of slots.
+type bookletPage struct {
+	boards  []*Board
+	numbers []int
+	columns int
+	rows    int
+}
+
+func layoutBooklet(entries []Entry, options BookletOptions) ([]bookletPage, error) {
+	if len(entries) == 0 {
+		return nil, errors.New("booklet has no puzzles")
+	}
+	perPage := options.PerPage
+	if perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	columns := int(math.Ceil(math.Sqrt(float64(perPage))))
+	rows := (perPage + columns - 1) / columns
+
+	var puzzles, solutions bookletPage
+	var pages []bookletPage
+	flush := func(page *bookletPage) {
+		if len(page.boards) > 0 {
+			page.columns, page.rows = columns, rows
+			pages = append(pages, *page)
+			*page = bookletPage{}
+		}
+	}
+	for i, entry := range entries {
+		board, err := NewBoard(entry.Puzzle, nil, ShowPuzzle)
+		if err != nil {
+			return nil, err
+		}
+		puzzles.boards = append(puzzles.boards, board)
+		puzzles.numbers = append(puzzles.numbers, i+1)
+		if len(puzzles.boards) == perPage {
+			flush(&puzzles)
+		}
+	}
+	flush(&puzzles)
+	for i, entry := range entries {
+		if entry.Solution == nil {
+			continue
+		}
+		board, err := NewBoard(entry.Puzzle, entry.Solution, ShowSolution)
+		if err != nil {
+			return nil, err
+		}
+		solutions.boards = append(solutions.boards, board)
+		solutions.numbers = append(solutions.numbers, i+1)
+		if len(solutions.boards) == perPage {
+			flush(&solutions)
+		}
+	}
+	flush(&solutions)
+	return pages, nil
+}
+
+func (p bookletPage) draw(c canvas) {
+	slotWidth := (pageWidth - 2*pageMargin) / float64(p.columns)
+	slotHeight := (pageHeight - 2*pageMargin) / float64(p.rows)
+	for i, board := range p.boards {
+		left := pageMargin + float64(i%p.columns)*slotWidth
+		top := pageMargin + float64(i/p.columns)*slotHeight
+		side := math.Min(slotWidth, slotHeight-labelHeight) * 0.9
+		cell := side / float64(board.Size)
+		side = cell * float64(board.Size)
+		boardLeft := left + (slotWidth-side)/2
+		c.text(boardLeft+side/2, top+labelHeight/2, 14, strconv.Itoa(p.numbers[i])+".", inkColor, true)
+		drawBoard(c, board, boardLeft, top+labelHeight, cell)
+	}
+}
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"image/color"
+	"strconv"
+)
+
+// canvas is a drawing surface in a top-left origin coordinate system. The
+// SVG, PNG and PDF outputs all draw boards through it.
+type canvas interface {
+	line(x1, y1, x2, y2, width float64, c color.Color)
+	// text draws s centred on (x, y).
+	text(x, y, size float64, s string, c color.Color, bold bool)
+}
+
+var (
+	gridColor   = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	inkColor    = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	solvedColor = color.RGBA{0x1f, 0x5f, 0xbf, 0xff}
+)
+
+// drawBoard draws the board with its top left corner at (left, top): thin
+// lines between all cells, thick lines around regions and the board, givens
+// in bold black and solved numbers in blue.
+func drawBoard(c canvas, board *Board, left, top, cell float64) {
+	side := float64(board.Size) * cell
+	for i := 1; i < board.Size; i++ {
+		p := float64(i) * cell
+		c.line(left+p, top, left+p, top+side, 1, gridColor)
+		c.line(left, top+p, left+side, top+p, 1, gridColor)
+	}
+
+	thick := cell / 12
+	if thick < 2 {
+		thick = 2
+	}
+	for x := 0; x < board.Size; x++ {
+		for y := 0; y < board.Size; y++ {
+			cx, cy := left+float64(y)*cell, top+float64(x)*cell
+			if board.Right[x][y] {
+				c.line(cx+cell, cy, cx+cell, cy+cell, thick, inkColor)
+			}
+			if board.Below[x][y] {
+				c.line(cx, cy+cell, cx+cell, cy+cell, thick, inkColor)
+			}
+		}
+	}
+	c.line(left, top, left+side, top, thick, inkColor)
+	c.line(left, top+side, left+side, top+side, thick, inkColor)
+	c.line(left, top, left, top+side, thick, inkColor)
+	c.line(left+side, top, left+side, top+side, thick, inkColor)
+
+	for x := 0; x < board.Size; x++ {
+		for y := 0; y < board.Size; y++ {
+			value := board.Values[x][y]
+			if value == 0 {
+				continue
+			}
+			colour := solvedColor
+			if board.Givens[x][y] {
+				colour = inkColor
+			}
+			c.text(left+float64(y)*cell+cell/2, top+float64(x)*cell+cell/2, cell*3/5, strconv.Itoa(value), colour, board.Givens[x][y])
+		}
+	}
+}
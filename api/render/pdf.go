@@ -0,0 +1,89 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// A4 page size in points.
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+)
+
+// pdfCanvas collects the content stream of one page. Text uses the
+// standard Helvetica fonts, which readers provide, so nothing is embedded.
+type pdfCanvas struct {
+	content bytes.Buffer
+}
+
+func (c *pdfCanvas) line(x1, y1, x2, y2, width float64, colour color.Color) {
+	r, g, b := pdfColor(colour)
+	fmt.Fprintf(&c.content, "%.3f %.3f %.3f RG %.2f w 2 J %.2f %.2f m %.2f %.2f l S\n",
+		r, g, b, width, x1, pageHeight-y1, x2, pageHeight-y2)
+}
+
+func (c *pdfCanvas) text(x, y, size float64, s string, colour color.Color, bold bool) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	r, g, b := pdfColor(colour)
+	// Helvetica digits are all 0.556 em wide, which is close enough for
+	// centring the few other characters boards use.
+	width := float64(len(s)) * 0.556 * size
+	fmt.Fprintf(&c.content, "%.3f %.3f %.3f rg BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+		r, g, b, font, size, x-width/2, pageHeight-y-0.35*size, pdfEscape(s))
+}
+
+func pdfColor(colour color.Color) (float64, float64, float64) {
+	r, g, b, _ := colour.RGBA()
+	return float64(r) / 0xffff, float64(g) / 0xffff, float64(b) / 0xffff
+}
+
+func pdfEscape(s string) string {
+	var b bytes.Buffer
+	for _, ch := range []byte(s) {
+		if ch == '(' || ch == ')' || ch == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// writePDF writes a document with one A4 page per canvas.
+func writePDF(w io.Writer, pages []*pdfCanvas) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	kids := ""
+	for i := range pages {
+		kids += fmt.Sprintf("%d 0 R ", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold >>")
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
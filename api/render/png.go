@@ -0,0 +1,101 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+)
+
+// PNG draws the board as a PNG image.
+func PNG(w io.Writer, board *Board, options Options) error {
+	cell := options.cellSize()
+	side := board.Size*cell + cell
+	img := newRaster(side, side, 1)
+	drawBoard(img, board, float64(cell/2), float64(cell/2), float64(cell))
+	return png.Encode(w, img.img)
+}
+
+// raster is a canvas backed by an RGBA image. Coordinates are multiplied by
+// scale, so page layouts in points can be drawn at any resolution. Only
+// horizontal and vertical lines are supported, which is all boards need.
+type raster struct {
+	img   *image.RGBA
+	scale float64
+}
+
+func newRaster(width, height int, scale float64) *raster {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	return &raster{img: img, scale: scale}
+}
+
+func (r *raster) line(x1, y1, x2, y2, width float64, c color.Color) {
+	half := math.Max(width*r.scale, 1) / 2
+	x1, x2 = math.Min(x1, x2)*r.scale, math.Max(x1, x2)*r.scale
+	y1, y2 = math.Min(y1, y2)*r.scale, math.Max(y1, y2)*r.scale
+	rect := image.Rect(round(x1-half), round(y1-half), round(x2+half), round(y2+half))
+	draw.Draw(r.img, rect, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// text draws with the built-in glyphs, scaled to whole pixels. Characters
+// without a glyph are left out.
+func (r *raster) text(x, y, size float64, s string, c color.Color, bold bool) {
+	pixel := int(size * r.scale / (glyphHeight + 2))
+	if pixel < 1 {
+		pixel = 1
+	}
+	extra := 0
+	if bold {
+		extra = (pixel + 2) / 3
+	}
+	advance := (glyphWidth + 1) * pixel
+	width := len(s)*advance - pixel + extra
+	left := round(x*r.scale) - width/2
+	top := round(y*r.scale) - glyphHeight*pixel/2
+	fill := image.NewUniform(c)
+	for i, ch := range s {
+		glyph, ok := glyphs[ch]
+		if !ok {
+			continue
+		}
+		for row, bits := range glyph {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<uint(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				px := left + i*advance + col*pixel
+				py := top + row*pixel
+				draw.Draw(r.img, image.Rect(px, py, px+pixel+extra, py+pixel), fill, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
+
+func round(v float64) int {
+	return int(math.Floor(v + 0.5))
+}
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// glyphs is a 5x7 font for the characters boards and booklets use, one
+// bit mask per row.
+var glyphs = map[rune][glyphHeight]uint8{
+	'0': {0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},
+	'1': {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'2': {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+	'3': {0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},
+	'4': {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+	'5': {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+	'6': {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+	'7': {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+	'9': {0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c},
+	' ': {},
+}
@@ -0,0 +1,143 @@
+package render
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// Show selects what a rendered board contains.
+type Show int
+
+const (
+	// ShowPuzzle draws the givens only.
+	ShowPuzzle Show = iota
+	// ShowSolution draws the solved board, givens styled apart from the
+	// numbers the solver filled in.
+	ShowSolution
+)
+
+// Options controls the look of a rendered board. Zero values pick the
+// defaults.
+type Options struct {
+	Show     Show
+	CellSize int
+}
+
+const defaultCellSize = 40
+
+func (o Options) cellSize() int {
+	if o.CellSize <= 0 {
+		return defaultCellSize
+	}
+	return o.CellSize
+}
+
+// Board is what the renderers draw: the numbers to show, which of them are
+// givens and the thick borders between regions.
+type Board struct {
+	Size   int
+	Values [][]int
+	Givens [][]bool
+	// Right[x][y] is a thick border right of cell (x, y), Below[x][y] one
+	// below it.
+	Right, Below [][]bool
+}
+
+// NewBoard prepares a board for drawing. The solution is only needed, and
+// only used, with ShowSolution. Regions come from GetInvolved on the shown
+// state; a border is thick where two numbered cells belong to different
+// regions.
+func NewBoard(puzzle, solution *solver.FieldState, show Show) (*Board, error) {
+	shown := puzzle
+	if show == ShowSolution {
+		if solution == nil {
+			return nil, errors.New("no solution to show")
+		}
+		shown = solution
+	}
+	clues := puzzle.ToList()
+	values := shown.ToList()
+	size := len(values)
+	if len(clues) != size {
+		return nil, errors.New("puzzle and solution differ in size")
+	}
+
+	region := make([][]int, size)
+	for x := range region {
+		region[x] = make([]int, size)
+	}
+	next := 1
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			if region[x][y] != 0 || values[x][y] == 0 {
+				continue
+			}
+			for _, cell := range shown.GetInvolved(solver.Cell{X: x, Y: y}) {
+				region[cell.X][cell.Y] = next
+			}
+			next++
+		}
+	}
+
+	b := &Board{Size: size, Values: values}
+	b.Givens = make([][]bool, size)
+	b.Right = make([][]bool, size)
+	b.Below = make([][]bool, size)
+	for x := 0; x < size; x++ {
+		b.Givens[x] = make([]bool, size)
+		b.Right[x] = make([]bool, size)
+		b.Below[x] = make([]bool, size)
+		for y := 0; y < size; y++ {
+			b.Givens[x][y] = clues[x][y] != 0
+			if y+1 < size {
+				b.Right[x][y] = region[x][y] != 0 && region[x][y+1] != 0 && region[x][y] != region[x][y+1]
+			}
+			if x+1 < size {
+				b.Below[x][y] = region[x][y] != 0 && region[x+1][y] != 0 && region[x][y] != region[x+1][y]
+			}
+		}
+	}
+	return b, nil
+}
+
+// SVG draws the board as a standalone SVG document.
+func SVG(w io.Writer, board *Board, options Options) error {
+	cell := options.cellSize()
+	margin := cell / 2
+	side := board.Size*cell + 2*margin
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", side, side, side, side)
+	fmt.Fprintf(out, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", side, side)
+	drawBoard(svgCanvas{out}, board, float64(margin), float64(margin), float64(cell))
+	fmt.Fprintf(out, "</svg>\n")
+	return out.Flush()
+}
+
+type svgCanvas struct {
+	out *bufio.Writer
+}
+
+func (c svgCanvas) line(x1, y1, x2, y2, width float64, colour color.Color) {
+	fmt.Fprintf(c.out, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="%s" stroke-width="%g" stroke-linecap="square"/>`+"\n",
+		x1, y1, x2, y2, hexColor(colour), width)
+}
+
+func (c svgCanvas) text(x, y, size float64, s string, colour color.Color, bold bool) {
+	weight := ""
+	if bold {
+		weight = ` font-weight="bold"`
+	}
+	fmt.Fprintf(c.out, `<text x="%g" y="%g" font-family="sans-serif" font-size="%g" text-anchor="middle" dominant-baseline="central" fill="%s"%s>%s</text>`+"\n",
+		x, y, size, hexColor(colour), weight, s)
+}
+
+func hexColor(colour color.Color) string {
+	r, g, b, _ := colour.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/alcoccoque/puzzle-solver-go/api/controllers"
-	"github.com/alcoccoque/puzzle-solver-go/api/seed"
 )
 
 var server = controllers.Server{}
@@ -31,8 +30,6 @@ func Run() {
 
 	server.Initialize(os.Getenv("DB_DRIVER"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_PORT"), os.Getenv("DB_HOST"), os.Getenv("DB_NAME"))
 
-	seed.Load(server.DB)
-
 	server.Run(":8080")
 
 }
@@ -0,0 +1,136 @@
+package solver
+
+// dlx is Knuth's Dancing Links engine for exact cover with secondary
+// columns. Primary columns must be covered exactly once, secondary columns
+// at most once. Nodes live in flat slices: node 0 is the root, nodes
+// 1..columns are the column headers and every row adds one node per column.
+type dlx struct {
+	left, right, up, down []int
+	column, row           []int
+	count                 []int
+	rows                  int
+	chosen                []int
+
+	// cancel stops the search when closed, leaving cancelled set.
+	cancel    <-chan struct{}
+	cancelled bool
+}
+
+func newDLX(primary, secondary int) *dlx {
+	columns := primary + secondary
+	d := &dlx{count: make([]int, columns+1)}
+	for i := 0; i <= columns; i++ {
+		d.left = append(d.left, i)
+		d.right = append(d.right, i)
+		d.up = append(d.up, i)
+		d.down = append(d.down, i)
+		d.column = append(d.column, i)
+		d.row = append(d.row, -1)
+	}
+	for i := 1; i <= primary; i++ {
+		d.left[i] = i - 1
+		d.right[i-1] = i
+	}
+	d.left[0] = primary
+	d.right[primary] = 0
+	return d
+}
+
+// addRow adds a row covering the given zero-based columns and returns its
+// number.
+func (d *dlx) addRow(columns []int) int {
+	first := -1
+	for _, c := range columns {
+		header := c + 1
+		node := len(d.left)
+		d.column = append(d.column, header)
+		d.row = append(d.row, d.rows)
+		d.up = append(d.up, d.up[header])
+		d.down = append(d.down, header)
+		d.down[d.up[header]] = node
+		d.up[header] = node
+		d.count[header]++
+		if first == -1 {
+			first = node
+			d.left = append(d.left, node)
+			d.right = append(d.right, node)
+			continue
+		}
+		d.left = append(d.left, d.left[first])
+		d.right = append(d.right, first)
+		d.right[d.left[first]] = node
+		d.left[first] = node
+	}
+	d.rows++
+	return d.rows - 1
+}
+
+func (d *dlx) cover(c int) {
+	d.right[d.left[c]] = d.right[c]
+	d.left[d.right[c]] = d.left[c]
+	for i := d.down[c]; i != c; i = d.down[i] {
+		for j := d.right[i]; j != i; j = d.right[j] {
+			d.down[d.up[j]] = d.down[j]
+			d.up[d.down[j]] = d.up[j]
+			d.count[d.column[j]]--
+		}
+	}
+}
+
+func (d *dlx) uncover(c int) {
+	for i := d.up[c]; i != c; i = d.up[i] {
+		for j := d.left[i]; j != i; j = d.left[j] {
+			d.count[d.column[j]]++
+			d.down[d.up[j]] = j
+			d.up[d.down[j]] = j
+		}
+	}
+	d.right[d.left[c]] = c
+	d.left[d.right[c]] = c
+}
+
+// search looks for exact covers and calls visit with the rows of each one.
+// It stops when visit returns true and returns the number of covers seen.
+func (d *dlx) search(visit func(rows []int) bool) int {
+	found := 0
+	var recurse func() bool
+	recurse = func() bool {
+		if d.cancelled || closed(d.cancel) {
+			d.cancelled = true
+			return true
+		}
+		if d.right[0] == 0 {
+			found++
+			return visit(d.chosen)
+		}
+		best := d.right[0]
+		for c := d.right[best]; c != 0; c = d.right[c] {
+			if d.count[c] < d.count[best] {
+				best = c
+			}
+		}
+		if d.count[best] == 0 {
+			return false
+		}
+
+		d.cover(best)
+		defer d.uncover(best)
+		for r := d.down[best]; r != best; r = d.down[r] {
+			d.chosen = append(d.chosen, d.row[r])
+			for j := d.right[r]; j != r; j = d.right[j] {
+				d.cover(d.column[j])
+			}
+			stop := recurse()
+			for j := d.left[r]; j != r; j = d.left[j] {
+				d.uncover(d.column[j])
+			}
+			d.chosen = d.chosen[:len(d.chosen)-1]
+			if stop {
+				return true
+			}
+		}
+		return false
+	}
+	recurse()
+	return found
+}
@@ -0,0 +1,189 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxExactCoverRows bounds the placements generated for the exact-cover
+// model. Boards that need more are better served by the backtracking modes.
+const maxExactCoverRows = 500000
+
+// maxExactCoverSize is the largest board side the exact-cover model takes.
+// Listing the placements of larger boards can run for minutes before the
+// row bound is reached.
+const maxExactCoverSize = 10
+
+// cancelCheckInterval is how many enumeration steps pass between looks at
+// the cancel channel.
+const cancelCheckInterval = 1024
+
+var errExactCoverTooLarge = errors.New("board is too large for the exact cover solver")
+
+// placement is a polyomino of size value at a fixed position on the board.
+type placement struct {
+	cells []int
+	value int
+}
+
+// solveExactCover models the board as exact cover: every legal placement is
+// a row, every cell a primary column, and for every edge between two cells
+// and every size there is a secondary column taken by the placements of
+// that size the edge leaves. Two regions of the same size can then never
+// touch. It returns up to limit covers and the first one as cell values.
+func solveExactCover(fieldState *FieldState, limit int, cancel <-chan struct{}) (int, []int, error) {
+	if size := fieldState.field.Size(); size > maxExactCoverSize {
+		return 0, nil, fmt.Errorf("exact cover mode supports boards up to %dx%d, got %dx%d", maxExactCoverSize, maxExactCoverSize, size, size)
+	}
+	rs := newRegionSet(fieldState.field)
+	given := make([]int, len(rs.value))
+	for _, cell := range fieldState.field.GetAllCells() {
+		given[rs.index(cell)] = fieldState.GetState(cell)
+	}
+	placements, err := enumeratePlacements(rs.neighbours, given, cancel)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	secondary := make(map[[3]int]int)
+	rows := make([][]int, len(placements))
+	for r, p := range placements {
+		inside := make(map[int]bool, len(p.cells))
+		for _, c := range p.cells {
+			inside[c] = true
+			rows[r] = append(rows[r], c)
+		}
+		for _, c := range p.cells {
+			for _, n := range rs.neighbours[c] {
+				if inside[n] {
+					continue
+				}
+				a, b := c, n
+				if a > b {
+					a, b = b, a
+				}
+				key := [3]int{a, b, p.value}
+				column, ok := secondary[key]
+				if !ok {
+					column = len(given) + len(secondary)
+					secondary[key] = column
+				}
+				rows[r] = append(rows[r], column)
+			}
+		}
+	}
+
+	d := newDLX(len(given), len(secondary))
+	d.cancel = cancel
+	for _, columns := range rows {
+		d.addRow(columns)
+	}
+	var solution []int
+	found := 0
+	d.search(func(chosen []int) bool {
+		found++
+		if solution == nil {
+			solution = make([]int, len(given))
+			for _, r := range chosen {
+				for _, c := range placements[r].cells {
+					solution[c] = placements[r].value
+				}
+			}
+		}
+		return found >= limit
+	})
+	if d.cancelled {
+		return 0, nil, ErrCancelled
+	}
+	return found, solution, nil
+}
+
+// enumeratePlacements lists every placement consistent with the givens: a
+// polyomino of size k may only use empty cells and givens of k, and must
+// contain every given of k it touches. Each polyomino is generated once,
+// from its lowest cell, with Redelmeier's algorithm. It fails with
+// ErrCancelled once cancel is closed.
+func enumeratePlacements(neighbours [][]int, given []int, cancel <-chan struct{}) ([]placement, error) {
+	var placements []placement
+	seen := make([]bool, len(given))
+	steps := 0
+	var stopped error
+
+	for k := 1; k <= len(given); k++ {
+		allowed := func(c int) bool {
+			return given[c] == 0 || given[c] == k
+		}
+		for start := range given {
+			if !allowed(start) {
+				continue
+			}
+			var extend func(untried, chosen []int) bool
+			extend = func(untried, chosen []int) bool {
+				steps++
+				if steps%cancelCheckInterval == 0 && closed(cancel) {
+					stopped = ErrCancelled
+					return false
+				}
+				if len(chosen) == k {
+					if closedPlacement(neighbours, given, chosen, k) {
+						placements = append(placements, placement{append([]int(nil), chosen...), k})
+					}
+					if len(placements) > maxExactCoverRows {
+						stopped = errExactCoverTooLarge
+						return false
+					}
+					return true
+				}
+				for idx, c := range untried {
+					var added []int
+					for _, n := range neighbours[c] {
+						if n > start && !seen[n] && allowed(n) {
+							seen[n] = true
+							added = append(added, n)
+						}
+					}
+					next := append(append([]int(nil), untried[idx+1:]...), added...)
+					ok := extend(next, append(chosen, c))
+					for _, n := range added {
+						seen[n] = false
+					}
+					if !ok {
+						return false
+					}
+				}
+				return true
+			}
+
+			seen[start] = true
+			var untried []int
+			for _, n := range neighbours[start] {
+				if n > start && allowed(n) {
+					seen[n] = true
+					untried = append(untried, n)
+				}
+			}
+			ok := extend(untried, []int{start})
+			for _, n := range untried {
+				seen[n] = false
+			}
+			seen[start] = false
+			if !ok {
+				return nil, stopped
+			}
+		}
+	}
+	return placements, nil
+}
+
+// closedPlacement reports whether no given of value k touches the
+// polyomino from outside, which would make the region larger than k.
+func closedPlacement(neighbours [][]int, given []int, cells []int, k int) bool {
+	for _, c := range cells {
+		for _, n := range neighbours[c] {
+			if given[n] == k && !contains(cells, n) {
+				return false
+			}
+		}
+	}
+	return true
+}
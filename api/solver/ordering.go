@@ -0,0 +1,202 @@
+package solver
+
+import "sort"
+
+// CellOrder selects which empty cell the search branches on next.
+type CellOrder int
+
+const (
+	// OrderFewestCandidates picks the cell with the fewest values that
+	// survive a trial assignment, preferring cells next to unfinished
+	// regions on ties. A cell with a single value left is filled before any
+	// real branching happens, which acts as propagation.
+	OrderFewestCandidates CellOrder = iota
+	// OrderRegionAdjacent picks cells bordering unfinished regions first and
+	// breaks ties by the number of candidates.
+	OrderRegionAdjacent
+	// OrderRowMajor fills cells from the bottom-right corner backwards, as
+	// the original solver did.
+	OrderRowMajor
+)
+
+// ValueOrder selects the order in which candidate values are tried.
+type ValueOrder int
+
+const (
+	// ValueRegionDemand tries the values of adjacent unfinished regions
+	// first, starting with the region closest to completion, and the
+	// remaining values in ascending order.
+	ValueRegionDemand ValueOrder = iota
+	// ValueAscending tries candidates in increasing order.
+	ValueAscending
+	// ValueRandom tries the values of adjacent unfinished regions first and
+	// the others in a random order biased towards small values, drawn from
+	// SolveOptions.Seed and the moves leading to the node. Different seeds
+	// find different solutions; the same seed finds the same one with any
+	// number of workers.
+	ValueRandom
+)
+
+// chooseCell returns the next cell to branch on and its values in the
+// order they should be tried. For the dynamic orders the values are already
+// filtered by a trial assignment; an empty list means the node is dead.
+func (s *search) chooseCell() (int, []int) {
+	if s.options.CellOrder == OrderRowMajor {
+		for k := len(s.free) - 1; k >= 0; k-- {
+			if i := s.free[k]; s.regions.value[i] == 0 {
+				return i, s.orderValues(i, s.candidates[i])
+			}
+		}
+	}
+
+	// Only cells touching a filled cell are scored: an isolated cell keeps
+	// nearly all of its candidates and would never be picked anyway.
+	best, bestValues, bestAdjacent := -1, []int(nil), false
+	for _, i := range s.free {
+		if s.regions.value[i] != 0 || !s.touchesFilled(i) {
+			continue
+		}
+		adjacent := s.unfinishedNeighbours(i) > 0
+		bound := -1
+		if best != -1 && (s.options.CellOrder != OrderRegionAdjacent || adjacent == bestAdjacent) {
+			bound = len(bestValues)
+		}
+		values := s.legalValues(i, bound)
+		if len(values) == 0 {
+			return i, nil
+		}
+		if best == -1 || s.better(len(values), adjacent, len(bestValues), bestAdjacent) {
+			best, bestValues, bestAdjacent = i, values, adjacent
+		}
+	}
+	if best == -1 {
+		for _, i := range s.free {
+			if s.regions.value[i] == 0 {
+				return i, s.orderValues(i, s.legalValues(i, -1))
+			}
+		}
+	}
+	return best, s.orderValues(best, bestValues)
+}
+
+func (s *search) better(count int, adjacent bool, bestCount int, bestAdjacent bool) bool {
+	if s.options.CellOrder == OrderRegionAdjacent && adjacent != bestAdjacent {
+		return adjacent
+	}
+	if count != bestCount {
+		return count < bestCount
+	}
+	return adjacent && !bestAdjacent
+}
+
+// legalValues returns the candidates of cell i that do not immediately
+// break a region. Once more than bound values are found the cell can no
+// longer win, so the scan stops early; a negative bound scans everything.
+func (s *search) legalValues(i, bound int) []int {
+	var values []int
+	for _, value := range s.candidates[i] {
+		mark := s.regions.mark()
+		if s.regions.assign(i, value) {
+			values = append(values, value)
+		}
+		s.regions.undo(mark)
+		if bound >= 0 && len(values) > bound {
+			break
+		}
+	}
+	return values
+}
+
+func (s *search) touchesFilled(i int) bool {
+	for _, n := range s.regions.neighbours[i] {
+		if s.regions.value[n] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unfinishedNeighbours counts the neighbours of i that belong to a region
+// still short of its value.
+func (s *search) unfinishedNeighbours(i int) int {
+	count := 0
+	for _, n := range s.regions.neighbours[i] {
+		if value := s.regions.value[n]; value != 0 && s.regions.regionSize(n) < value {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *search) orderValues(i int, values []int) []int {
+	if s.options.ValueOrder == ValueAscending || len(values) < 2 {
+		return values
+	}
+	demand := make(map[int]int)
+	for _, n := range s.regions.neighbours[i] {
+		value := s.regions.value[n]
+		if value == 0 {
+			continue
+		}
+		if missing := value - s.regions.regionSize(n); missing > 0 {
+			if current, ok := demand[value]; !ok || missing < current {
+				demand[value] = missing
+			}
+		}
+	}
+	if s.options.ValueOrder == ValueRandom {
+		return s.shuffleValues(values, demand)
+	}
+	if len(demand) == 0 {
+		return values
+	}
+	ordered := append([]int(nil), values...)
+	sort.SliceStable(ordered, func(a, b int) bool {
+		da, okA := demand[ordered[a]]
+		db, okB := demand[ordered[b]]
+		if okA != okB {
+			return okA
+		}
+		return okA && da < db
+	})
+	return ordered
+}
+
+// shuffleValues orders values for ValueRandom. Each value gets a random key
+// scaled by the value, so large regions are tried later without being ruled
+// out; demanded values come first.
+func (s *search) shuffleValues(values []int, demand map[int]int) []int {
+	keys := make(map[int]float64, len(values))
+	for _, value := range values {
+		keys[value] = float64(value) * s.draw(value)
+		if _, ok := demand[value]; ok {
+			keys[value] -= float64(len(values) * len(values))
+		}
+	}
+	ordered := append([]int(nil), values...)
+	sort.Slice(ordered, func(a, b int) bool {
+		return keys[ordered[a]] < keys[ordered[b]]
+	})
+	return ordered
+}
+
+// draw returns a number in [0, 1) for a value at the current node. It only
+// depends on the seed, the path and the value, unlike a stream shared by
+// the whole search, whose state at a node would depend on every node
+// visited before it.
+func (s *search) draw(value int) float64 {
+	return float64(mix(uint64(s.options.Seed)^mix(s.path^uint64(value)))>>11) / (1 << 53)
+}
+
+// pathStep extends the path of a node by one move.
+func pathStep(path uint64, m move) uint64 {
+	return mix(path ^ uint64(m.cell)<<32 ^ uint64(m.value))
+}
+
+// mix scrambles x with the finalizer of SplitMix64.
+func mix(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ x>>30) * 0xBF58476D1CE4E5B9
+	x = (x ^ x>>27) * 0x94D049BB133111EB
+	return x ^ x>>31
+}
@@ -0,0 +1,136 @@
+package solver
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// tasksPerWorker controls how finely the top of the search tree is split.
+// More tasks than workers keeps every goroutine busy when branches differ
+// wildly in size.
+const tasksPerWorker = 8
+
+// sharedSearch coordinates the branches of a parallel search. Tasks are
+// numbered in sequential search order, so the solution reported is the one
+// found by the lowest task, exactly as the sequential search would find it.
+type sharedSearch struct {
+	limit     int64
+	solutions int64
+	first     int64
+}
+
+func (sh *sharedSearch) record(task int) {
+	atomic.AddInt64(&sh.solutions, 1)
+	for {
+		first := atomic.LoadInt64(&sh.first)
+		if int64(task) >= first || atomic.CompareAndSwapInt64(&sh.first, first, int64(task)) {
+			return
+		}
+	}
+}
+
+// stopped reports whether the task can give up: enough solutions are known
+// and a task earlier in search order already holds one.
+func (sh *sharedSearch) stopped(task int) bool {
+	return atomic.LoadInt64(&sh.solutions) >= sh.limit && int64(task) >= atomic.LoadInt64(&sh.first)
+}
+
+// prefix is a consistent path from the root of the tree, as the moves
+// along it and the number of levels they span.
+type prefix struct {
+	moves []move
+	depth int
+}
+
+// split enumerates, in search order, the consistent paths through the top
+// levels of the tree, going deeper until there are at least target of them.
+func (s *search) split(target int) []prefix {
+	var prefixes []prefix
+	for depth := 1; depth <= len(s.free); depth++ {
+		prefixes = prefixes[:0]
+		s.collectPrefixes(depth, prefix{}, &prefixes)
+		if len(prefixes) >= target || len(prefixes) == 0 {
+			break
+		}
+	}
+	if len(s.free) == 0 {
+		prefixes = append(prefixes, prefix{})
+	}
+	return prefixes
+}
+
+func (s *search) collectPrefixes(depth int, current prefix, prefixes *[]prefix) {
+	if depth == 0 || s.complete() {
+		*prefixes = append(*prefixes, prefix{moves: append([]move(nil), current.moves...), depth: current.depth})
+		return
+	}
+	for _, alternative := range s.branches() {
+		mark, path := s.regions.mark(), s.path
+		if s.apply(alternative) {
+			s.collectPrefixes(depth-1, prefix{moves: append(current.moves, alternative...), depth: current.depth + 1}, prefixes)
+		}
+		s.regions.undo(mark)
+		s.path = path
+	}
+}
+
+// runParallel splits the search tree into tasks and explores them on a pool
+// of workers. Counts are summed across tasks and capped at limit, so the
+// result matches the sequential search. Each task starts at the depth and
+// path of its prefix, so it reports depths and draws random value orders as
+// the sequential search would below that node.
+func runParallel(fieldState *FieldState, possibleValues map[Cell][]int, limit int, options SolveOptions) (int, []int, error) {
+	workers := options.Workers
+	root := newSearch(fieldState, possibleValues, limit, options)
+	if root.failed {
+		return 0, nil, nil
+	}
+	tasks := root.split(workers * tasksPerWorker)
+	shared := &sharedSearch{limit: int64(limit), first: int64(len(tasks))}
+	branches := make([]*search, len(tasks))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range jobs {
+				s := &search{
+					regions:    root.regions.clone(),
+					candidates: root.candidates,
+					free:       root.free,
+					limit:      limit,
+					options:    options,
+					shared:     shared,
+					task:       task,
+					depth:      tasks[task].depth,
+				}
+				s.apply(tasks[task].moves)
+				s.backtrack()
+				branches[task] = s
+			}
+		}()
+	}
+	for task := range tasks {
+		jobs <- task
+	}
+	close(jobs)
+	wg.Wait()
+
+	count := 0
+	var solution []int
+	for _, s := range branches {
+		if s.cancelled {
+			return 0, nil, ErrCancelled
+		}
+		count += s.solutions
+		if solution == nil {
+			solution = s.solution
+		}
+	}
+	if count > limit {
+		count = limit
+	}
+	return count, solution, nil
+}
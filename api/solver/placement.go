@@ -0,0 +1,160 @@
+package solver
+
+// SearchMode selects what a level of the search tree decides.
+type SearchMode int
+
+const (
+	// ModeCells assigns a value to a single cell per level.
+	ModeCells SearchMode = iota
+	// ModeRegions picks the unfinished region closest to its value and
+	// branches on every legal polyomino that completes it. Cells are only
+	// assigned one at a time when no region is left unfinished, or when a
+	// region has too many completions to list.
+	ModeRegions
+	// ModeExactCover solves the board as an exact cover problem with
+	// Dancing Links. It is meant for exhaustive work on small boards, takes
+	// none larger than 10x10, and ignores Workers and the ordering options.
+	ModeExactCover
+)
+
+// maxCompletions caps the polyominoes listed for one region. Past it the
+// region is grown cell by cell instead.
+const maxCompletions = 4096
+
+// regionBranches returns one alternative per completion of the most
+// constrained unfinished region. It reports false when there is no region
+// to complete or the completions could not all be listed.
+func (s *search) regionBranches() ([][]move, bool) {
+	target := s.unfinishedRegion()
+	if target == -1 {
+		return nil, false
+	}
+	completions, ok := s.completions(target)
+	if !ok {
+		return nil, false
+	}
+	value := s.regions.value[target]
+	alternatives := make([][]move, len(completions))
+	for k, cells := range completions {
+		alternatives[k] = make([]move, len(cells))
+		for j, i := range cells {
+			alternatives[k][j] = move{i, value}
+		}
+	}
+	return alternatives, true
+}
+
+// unfinishedRegion returns a cell of the region missing the fewest cells,
+// preferring regions with fewer liberties, or -1 if every region is done.
+func (s *search) unfinishedRegion() int {
+	rs := s.regions
+	best, bestMissing, bestLiberties := -1, 0, 0
+	for i, value := range rs.value {
+		if value == 0 {
+			continue
+		}
+		root := rs.find(i)
+		missing := value - rs.cells[root]
+		if missing <= 0 {
+			continue
+		}
+		if best == -1 || missing < bestMissing || missing == bestMissing && rs.liberties[root] < bestLiberties {
+			best, bestMissing, bestLiberties = i, missing, rs.liberties[root]
+		}
+	}
+	return best
+}
+
+// completions lists, without repeats, every set of empty cells that grows
+// the region containing i to exactly its value. A cell touching another
+// region of the same value absorbs that region, whose cells then count
+// towards the value too. The enumeration follows Redelmeier's algorithm: a
+// cell enters the untried list at most once along any branch.
+func (s *search) completions(i int) ([][]int, bool) {
+	rs := s.regions
+	value := rs.value[i]
+
+	seen := make([]bool, len(rs.value))
+	absorbed := make(map[int]bool)
+
+	// absorb marks the cells of the region rooted at root as part of the
+	// polyomino and returns its empty neighbours that were not seen yet.
+	absorb := func(root int) []int {
+		absorbed[root] = true
+		var added []int
+		for c := range rs.value {
+			if rs.value[c] != value || rs.find(c) != root {
+				continue
+			}
+			for _, n := range rs.neighbours[c] {
+				if !seen[n] && rs.value[n] == 0 {
+					seen[n] = true
+					added = append(added, n)
+				}
+			}
+		}
+		return added
+	}
+
+	var completions [][]int
+	var extend func(untried, chosen []int, missing int) bool
+	extend = func(untried, chosen []int, missing int) bool {
+		if missing == 0 {
+			completions = append(completions, append([]int(nil), chosen...))
+			return len(completions) <= maxCompletions
+		}
+		for k, c := range untried {
+			need := missing - 1
+			var roots []int
+			for _, n := range rs.neighbours[c] {
+				if rs.value[n] != value {
+					continue
+				}
+				if root := rs.find(n); !absorbed[root] && !contains(roots, root) {
+					roots = append(roots, root)
+					need -= rs.cells[root]
+				}
+			}
+			if need < 0 {
+				continue
+			}
+
+			var added []int
+			for _, n := range rs.neighbours[c] {
+				if !seen[n] && rs.value[n] == 0 {
+					seen[n] = true
+					added = append(added, n)
+				}
+			}
+			for _, root := range roots {
+				added = append(added, absorb(root)...)
+			}
+			next := append(append([]int(nil), untried[k+1:]...), added...)
+			ok := extend(next, append(chosen, c), need)
+			for _, n := range added {
+				seen[n] = false
+			}
+			for _, root := range roots {
+				delete(absorbed, root)
+			}
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	root := rs.find(i)
+	untried := absorb(root)
+	ok := extend(untried, nil, value-rs.cells[root])
+	return completions, ok
+}
+
+func contains(items []int, item int) bool {
+	for _, it := range items {
+		if it == item {
+			return true
+		}
+	}
+	return false
+}
@@ -1,44 +1,106 @@
 package solver
 
 import (
-	"errors"
-	"fmt"
 	"math/rand"
 	"time"
 )
 
 type PuzzleGenerator struct {
-	size int
+	size     int
+	symmetry ClueSymmetry
+	cancel   <-chan struct{}
+	progress func(done, total int)
 }
 
 func NewPuzzleGenerator(size int) *PuzzleGenerator {
 	return &PuzzleGenerator{size: size}
 }
 
+// SetSymmetry makes the generator keep its clue layout symmetric.
+func (pg *PuzzleGenerator) SetSymmetry(symmetry ClueSymmetry) {
+	pg.symmetry = symmetry
+}
+
+// SetCancel stops generation when cancel is closed; the generator then
+// fails with ErrCancelled.
+func (pg *PuzzleGenerator) SetCancel(cancel <-chan struct{}) {
+	pg.cancel = cancel
+}
+
+// SetProgress registers a function called with the steps done out of the
+// total as generation advances.
+func (pg *PuzzleGenerator) SetProgress(progress func(done, total int)) {
+	pg.progress = progress
+}
+
+func (pg *PuzzleGenerator) report(done, total int) {
+	if pg.progress != nil {
+		pg.progress(done, total)
+	}
+}
+
+// GeneratePuzzle solves an almost empty board and clears cells of it until
+// about filledPercentage of them are left. Cells are cleared an orbit of
+// the generator's symmetry at a time, a single cell without one, and only
+// while the puzzle stays unique, so it may keep more clues than asked for.
 func (pg *PuzzleGenerator) GeneratePuzzle(filledPercentage float64) ([][]int, error) {
 	solvedPuzzle, err := pg.SolvePuzzle()
 	if err != nil {
 		return nil, err
 	}
+	filledCellsCount := int(float64(pg.size*pg.size) * filledPercentage)
+	return pg.removeOrbits(solvedPuzzle, filledCellsCount)
+}
 
-	totalCells := pg.size * pg.size
-	filledCellsCount := int(float64(totalCells) * filledPercentage)
-	var filledCells []Cell
-	for x := 0; x < pg.size; x++ {
-		for y := 0; y < pg.size; y++ {
-			if solvedPuzzle[x][y] != 0 {
-				filledCells = append(filledCells, Cell{x, y})
-			}
+// removeOrbits clears the orbits of the generator's symmetry in random
+// order, skipping any whose removal would leave fewer than target clues or
+// a second solution.
+func (pg *PuzzleGenerator) removeOrbits(grid [][]int, target int) ([][]int, error) {
+	clues := pg.size * pg.size
+	orbits := pg.symmetry.orbits(pg.size)
+	rand.Seed(time.Now().UnixNano())
+	rand.Shuffle(len(orbits), func(i, j int) {
+		orbits[i], orbits[j] = orbits[j], orbits[i]
+	})
+	for k, orbit := range orbits {
+		pg.report(k, len(orbits))
+		if clues-len(orbit) < target {
+			continue
+		}
+		values := make([]int, len(orbit))
+		for i, cell := range orbit {
+			values[i] = grid[cell.X][cell.Y]
+			grid[cell.X][cell.Y] = 0
+		}
+		ok, err := unique(grid, SolveOptions{Cancel: pg.cancel})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			clues -= len(orbit)
+			continue
+		}
+		for i, cell := range orbit {
+			grid[cell.X][cell.Y] = values[i]
 		}
 	}
+	pg.report(len(orbits), len(orbits))
+	return grid, nil
+}
 
-	rand.Seed(time.Now().UnixNano())
-	cellsToReset := randomSample(filledCells, len(filledCells)-filledCellsCount)
-	for _, cell := range cellsToReset {
-		solvedPuzzle[cell.X][cell.Y] = 0
+// unique reports whether the puzzle in grid has exactly one solution.
+func unique(grid [][]int, options SolveOptions) (bool, error) {
+	result, err := FromListToState(grid)
+	if err != nil {
+		return false, err
 	}
-
-	return solvedPuzzle, nil
+	ps := NewPuzzleSolver(result["state"].(*FieldState))
+	ps.SetOptions(options)
+	count, err := ps.CountSolutions(2)
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
 }
 
 func (pg *PuzzleGenerator) SolvePuzzle() ([][]int, error) {
@@ -59,6 +121,7 @@ func (pg *PuzzleGenerator) SolvePuzzle() ([][]int, error) {
 
 	fieldState := result["state"].(*FieldState)
 	solver := NewPuzzleSolver(fieldState)
+	solver.SetOptions(SolveOptions{Cancel: pg.cancel})
 	solution, err := solver.Solve()
 	if err != nil {
 		return nil, err
@@ -66,29 +129,3 @@ func (pg *PuzzleGenerator) SolvePuzzle() ([][]int, error) {
 
 	return solution["solved_puzzle"].([][]int), nil
 }
-
-// randomSample returns a random sample of n elements from the slice.
-func randomSample(cells []Cell, n int) []Cell {
-	if n > len(cells) {
-		n = len(cells)
-	}
-	rand.Seed(time.Now().UnixNano())
-	perm := rand.Perm(len(cells))
-	sample := make([]Cell, n)
-	for i := 0; i < n; i++ {
-		sample[i] = cells[perm[i]]
-	}
-	return sample
-}
-
-func main() {
-	pg := NewPuzzleGenerator(5)
-	puzzle, err := pg.GeneratePuzzle(0.5)
-	if err != nil {
-		fmt.Println("Error generating puzzle:", err)
-		return
-	}
-	for _, row := range puzzle {
-		fmt.Println(row)
-	}
-}
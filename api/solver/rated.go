@@ -0,0 +1,116 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrDifficultyMissed is returned by GenerateRated when no attempt gave a
+// puzzle of the asked difficulty.
+var ErrDifficultyMissed = errors.New("no puzzle of that difficulty found")
+
+// GenerateRated makes a unique puzzle rated at difficulty. Each attempt
+// draws a random solution and reduces it to a minimal puzzle, the hardest
+// that solution usually gives, then adds clues back from the solution in
+// random order until the rating comes down to difficulty. It gives up after
+// attempts draws.
+func (pg *PuzzleGenerator) GenerateRated(difficulty string, attempts int) ([][]int, error) {
+	target := difficultyRank(difficulty)
+	if target < 0 {
+		return nil, fmt.Errorf("unknown difficulty %q", difficulty)
+	}
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for attempt := 0; attempt < attempts; attempt++ {
+		pg.report(attempt, attempts)
+		if closed(pg.cancel) {
+			return nil, ErrCancelled
+		}
+		puzzle, err := pg.drawRated(target, random)
+		if err == ErrCancelled {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if puzzle != nil {
+			pg.report(attempts, attempts)
+			return puzzle, nil
+		}
+	}
+	return nil, ErrDifficultyMissed
+}
+
+// drawRated makes one attempt of GenerateRated, returning nil if the drawn
+// solution does not give a puzzle of the target rank. It fails with
+// ErrCancelled once the generator is cancelled.
+func (pg *PuzzleGenerator) drawRated(target int, random *rand.Rand) ([][]int, error) {
+	empty := make([][]int, pg.size)
+	for x := range empty {
+		empty[x] = make([]int, pg.size)
+	}
+	result, err := FromListToState(empty)
+	if err != nil {
+		return nil, err
+	}
+	ps := NewPuzzleSolver(result["state"].(*FieldState))
+	ps.SetOptions(SolveOptions{ValueOrder: ValueRandom, Seed: random.Int63(), Cancel: pg.timeout(drawTimeout)})
+	solved, err := ps.Solve()
+	if err != nil {
+		return nil, err
+	}
+	solution := solved["solved_puzzle"].([][]int)
+
+	result, err = FromListToState(solution)
+	if err != nil {
+		return nil, err
+	}
+	reduced, err := Reduce(result["state"].(*FieldState), ReduceOptions{Solve: SolveOptions{Cancel: pg.timeout(checkTimeout)}})
+	if err != nil {
+		return nil, err
+	}
+	puzzle := reduced.ToList()
+	var missing []Cell
+	for _, cell := range reduced.field.GetAllCells() {
+		if puzzle[cell.X][cell.Y] == 0 {
+			missing = append(missing, cell)
+		}
+	}
+	random.Shuffle(len(missing), func(i, j int) {
+		missing[i], missing[j] = missing[j], missing[i]
+	})
+
+	for {
+		result, err := FromListToState(puzzle)
+		if err != nil {
+			return nil, err
+		}
+		rater := NewPuzzleSolver(result["state"].(*FieldState))
+		rater.SetOptions(SolveOptions{Cancel: pg.cancel})
+		rating, err := rater.Rate()
+		if err != nil {
+			return nil, err
+		}
+		rank := difficultyRank(rating.Difficulty)
+		if rank == target {
+			return puzzle, nil
+		}
+		if rank < target || len(missing) == 0 {
+			return nil, nil
+		}
+		cell := missing[0]
+		missing = missing[1:]
+		puzzle[cell.X][cell.Y] = solution[cell.X][cell.Y]
+	}
+}
+
+// difficultyRank is the position of a difficulty in Difficulties, or -1.
+func difficultyRank(difficulty string) int {
+	for i, d := range Difficulties {
+		if d == difficulty {
+			return i
+		}
+	}
+	return -1
+}
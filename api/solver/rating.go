@@ -0,0 +1,65 @@
+package solver
+
+// Rating describes how much searching a puzzle takes.
+type Rating struct {
+	Givens int
+	// Solutions is the number of solutions, counted up to two.
+	Solutions int
+	// Nodes is the size of the search tree needed to find the solution and
+	// prove there is no other.
+	Nodes int
+	// Difficulty is empty unless the solution is unique.
+	Difficulty string
+}
+
+// Difficulty levels, from puzzles that never need a guess to ones whose
+// search tree is many times larger than the board.
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+	DifficultyExpert = "expert"
+)
+
+// Difficulties lists the difficulty levels from easiest to hardest.
+var Difficulties = []string{DifficultyEasy, DifficultyMedium, DifficultyHard, DifficultyExpert}
+
+// Rate searches the puzzle with the default cell ordering and rates it by
+// the number of search nodes per empty cell. The state itself is left
+// untouched. Of the options only Cancel is used.
+func (ps *PuzzleSolver) Rate() (Rating, error) {
+	if err := ps.refreshState(); err != nil {
+		return Rating{}, err
+	}
+	s := newSearch(ps.fieldState, ps.possibleValues, 2, SolveOptions{Cancel: ps.options.Cancel})
+	rating := Rating{Solutions: s.run(), Nodes: s.nodes}
+	if s.cancelled {
+		return Rating{}, ErrCancelled
+	}
+	for _, row := range ps.fieldState.ToList() {
+		for _, value := range row {
+			if value != 0 {
+				rating.Givens++
+			}
+		}
+	}
+	if rating.Solutions != 1 {
+		return rating, nil
+	}
+
+	empty := len(s.free)
+	if empty == 0 {
+		empty = 1
+	}
+	switch ratio := float64(rating.Nodes) / float64(empty); {
+	case ratio <= 1.5:
+		rating.Difficulty = DifficultyEasy
+	case ratio <= 4:
+		rating.Difficulty = DifficultyMedium
+	case ratio <= 16:
+		rating.Difficulty = DifficultyHard
+	default:
+		rating.Difficulty = DifficultyExpert
+	}
+	return rating, nil
+}
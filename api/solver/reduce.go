@@ -0,0 +1,81 @@
+package solver
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrNotUnique is returned by Reduce for puzzles without exactly one
+// solution.
+var ErrNotUnique = errors.New("puzzle does not have a unique solution")
+
+// ReduceOptions controls which givens Reduce tries to remove first and
+// which it keeps.
+type ReduceOptions struct {
+	// Pinned givens are never removed.
+	Pinned []Cell
+	// Prefer lists values whose givens are tried first, in that order.
+	// Givens of other values follow in row order.
+	Prefer []int
+	// Solve is used for the uniqueness checks.
+	Solve SolveOptions
+}
+
+// Reduce removes givens from a uniquely solvable puzzle until every one left
+// is needed for uniqueness, and returns the reduced puzzle. A given that
+// cannot go stays necessary once more givens are removed, so one pass over
+// them is enough. The state itself is left untouched.
+func Reduce(fieldState *FieldState, options ReduceOptions) (*FieldState, error) {
+	grid := fieldState.ToList()
+	ok, err := unique(grid, options.Solve)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotUnique
+	}
+
+	pinned := make(map[Cell]bool)
+	for _, cell := range options.Pinned {
+		pinned[cell] = true
+	}
+	rank := make(map[int]int)
+	for i, value := range options.Prefer {
+		if _, found := rank[value]; !found {
+			rank[value] = i
+		}
+	}
+	order := func(value int) int {
+		if i, found := rank[value]; found {
+			return i
+		}
+		return len(options.Prefer)
+	}
+
+	var givens []Cell
+	for _, cell := range fieldState.field.GetAllCells() {
+		if grid[cell.X][cell.Y] != 0 && !pinned[cell] {
+			givens = append(givens, cell)
+		}
+	}
+	sort.SliceStable(givens, func(i, j int) bool {
+		return order(grid[givens[i].X][givens[i].Y]) < order(grid[givens[j].X][givens[j].Y])
+	})
+
+	for _, cell := range givens {
+		value := grid[cell.X][cell.Y]
+		grid[cell.X][cell.Y] = 0
+		ok, err := unique(grid, options.Solve)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			grid[cell.X][cell.Y] = value
+		}
+	}
+	result, err := FromListToState(grid)
+	if err != nil {
+		return nil, err
+	}
+	return result["state"].(*FieldState), nil
+}
@@ -0,0 +1,221 @@
+package solver
+
+import "sort"
+
+// regionSet keeps the same-value regions of a board up to date while the
+// search assigns and clears cells. It is a union-find over cell indices
+// (x*size + y) with union by size and no path compression, so every change
+// can be reverted from the undo log in LIFO order.
+//
+// Liberties are counted as pseudo-liberties: the number of edges between a
+// region and empty cells. The count is zero exactly when the region has no
+// empty neighbour left, which is all the search needs to know.
+type regionSet struct {
+	size       int
+	value      []int
+	parent     []int
+	cells      []int
+	liberties  []int
+	neighbours [][]int
+	filled     int
+	log        []undoEntry
+
+	// anchors, when set, counts the anchor cells of each region; a region
+	// may only be completed if it holds one. See SolveOptions.anchors.
+	anchors []int
+
+	// visited and stamp back the bounded flood fill in hasRoom.
+	visited []int
+	stamp   int
+	queue   []int
+}
+
+type undoEntry struct {
+	ref *int
+	old int
+}
+
+func newRegionSet(field *Field) *regionSet {
+	n := field.Size() * field.Size()
+	rs := &regionSet{
+		size:       field.Size(),
+		value:      make([]int, n),
+		parent:     make([]int, n),
+		cells:      make([]int, n),
+		liberties:  make([]int, n),
+		neighbours: make([][]int, n),
+		visited:    make([]int, n),
+	}
+	for _, cell := range field.GetAllCells() {
+		i := rs.index(cell)
+		rs.parent[i] = i
+		rs.cells[i] = 1
+		for neighbour := range field.GetNeighbourCells(cell) {
+			rs.neighbours[i] = append(rs.neighbours[i], rs.index(neighbour))
+		}
+		// Map iteration order is random; keep the search deterministic.
+		sort.Ints(rs.neighbours[i])
+	}
+	return rs
+}
+
+func (rs *regionSet) index(cell Cell) int {
+	return cell.X*rs.size + cell.Y
+}
+
+func (rs *regionSet) cell(i int) Cell {
+	return Cell{i / rs.size, i % rs.size}
+}
+
+func (rs *regionSet) set(ref *int, value int) {
+	rs.log = append(rs.log, undoEntry{ref: ref, old: *ref})
+	*ref = value
+}
+
+func (rs *regionSet) mark() int {
+	return len(rs.log)
+}
+
+// undo reverts every change made since the given mark.
+func (rs *regionSet) undo(mark int) {
+	for len(rs.log) > mark {
+		entry := rs.log[len(rs.log)-1]
+		*entry.ref = entry.old
+		rs.log = rs.log[:len(rs.log)-1]
+	}
+}
+
+func (rs *regionSet) find(i int) int {
+	for rs.parent[i] != i {
+		i = rs.parent[i]
+	}
+	return i
+}
+
+// regionSize returns the number of cells in the region containing i.
+func (rs *regionSet) regionSize(i int) int {
+	return rs.cells[rs.find(i)]
+}
+
+// regionLiberties returns the pseudo-liberty count of the region containing i.
+func (rs *regionSet) regionLiberties(i int) int {
+	return rs.liberties[rs.find(i)]
+}
+
+func (rs *regionSet) union(a, b int) {
+	a, b = rs.find(a), rs.find(b)
+	if a == b {
+		return
+	}
+	if rs.cells[a] < rs.cells[b] {
+		a, b = b, a
+	}
+	rs.set(&rs.parent[b], a)
+	rs.set(&rs.cells[a], rs.cells[a]+rs.cells[b])
+	rs.set(&rs.liberties[a], rs.liberties[a]+rs.liberties[b])
+	if rs.anchors != nil {
+		rs.set(&rs.anchors[a], rs.anchors[a]+rs.anchors[b])
+	}
+}
+
+// setAnchors makes completing a region without one of the given cells a
+// dead end.
+func (rs *regionSet) setAnchors(cells []Cell) {
+	rs.anchors = make([]int, len(rs.value))
+	for _, cell := range cells {
+		rs.anchors[rs.index(cell)] = 1
+	}
+}
+
+// assign fills the empty cell i with value and merges it into the adjacent
+// regions of the same value. It reports false when the assignment leaves a
+// region larger than its value, closes a region before it reached its
+// value or completes one without an anchor. The changes are applied either
+// way; callers undo to a mark.
+func (rs *regionSet) assign(i, value int) bool {
+	rs.set(&rs.value[i], value)
+	rs.set(&rs.filled, rs.filled+1)
+	free := 0
+	for _, n := range rs.neighbours[i] {
+		if rs.value[n] == 0 {
+			free++
+			continue
+		}
+		root := rs.find(n)
+		rs.set(&rs.liberties[root], rs.liberties[root]-1)
+	}
+	rs.set(&rs.liberties[i], free)
+	for _, n := range rs.neighbours[i] {
+		if rs.value[n] == value {
+			rs.union(i, n)
+		}
+	}
+
+	root := rs.find(i)
+	if rs.cells[root] > value || !rs.hasRoom(i) {
+		return false
+	}
+	if rs.anchors != nil && rs.cells[root] == value && rs.anchors[root] == 0 {
+		return false
+	}
+	for _, n := range rs.neighbours[i] {
+		if rs.value[n] == 0 || rs.value[n] == value {
+			continue
+		}
+		if !rs.hasRoom(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasRoom reports whether the region containing i can still reach its
+// value: the empty cells reachable from it through empty or same-valued
+// cells must cover what is missing. The count is an over-estimate, so a
+// false answer is always a real dead end.
+func (rs *regionSet) hasRoom(i int) bool {
+	value, root := rs.value[i], rs.find(i)
+	missing := value - rs.cells[root]
+	if missing <= 0 {
+		return true
+	}
+	if rs.liberties[root] == 0 {
+		return false
+	}
+
+	rs.stamp++
+	rs.visited[i] = rs.stamp
+	rs.queue = append(rs.queue[:0], i)
+	for head := 0; head < len(rs.queue); head++ {
+		for _, n := range rs.neighbours[rs.queue[head]] {
+			if rs.visited[n] == rs.stamp || (rs.value[n] != 0 && rs.value[n] != value) {
+				continue
+			}
+			rs.visited[n] = rs.stamp
+			if rs.value[n] == 0 || rs.find(n) != root {
+				missing--
+				if missing == 0 {
+					return true
+				}
+			}
+			rs.queue = append(rs.queue, n)
+		}
+	}
+	return false
+}
+
+// clone returns an independent copy of the current regions. The neighbour
+// lists never change and are shared.
+func (rs *regionSet) clone() *regionSet {
+	return &regionSet{
+		size:       rs.size,
+		value:      append([]int(nil), rs.value...),
+		parent:     append([]int(nil), rs.parent...),
+		cells:      append([]int(nil), rs.cells...),
+		liberties:  append([]int(nil), rs.liberties...),
+		neighbours: rs.neighbours,
+		filled:     rs.filled,
+		visited:    make([]int, len(rs.visited)),
+		anchors:    append([]int(nil), rs.anchors...),
+	}
+}
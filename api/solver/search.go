@@ -0,0 +1,174 @@
+package solver
+
+// search is the backtracking engine behind PuzzleSolver. Givens are placed
+// once up front; afterwards empty cells are filled one alternative at a time
+// while regionSet rejects oversized or closed-off regions as soon as they
+// appear.
+type search struct {
+	regions    *regionSet
+	candidates [][]int
+	free       []int
+	limit      int
+	solutions  int
+	solution   []int
+	failed     bool
+	options    SolveOptions
+	// nodes counts the calls to backtrack, depth the levels below the
+	// root of the current one.
+	nodes     int
+	depth     int
+	cancelled bool
+	// path identifies the current node by the moves leading to it from
+	// the givens. ValueRandom draws from it, so a node orders its values
+	// the same whichever search reaches it.
+	path uint64
+
+	// shared and task are set when the search runs as one branch of a
+	// parallel search.
+	shared *sharedSearch
+	task   int
+}
+
+// progressInterval is the number of nodes between SolveOptions.Progress
+// calls.
+const progressInterval = 1024
+
+func newSearch(fieldState *FieldState, possibleValues map[Cell][]int, limit int, options SolveOptions) *search {
+	regions := newRegionSet(fieldState.field)
+	if options.anchors != nil {
+		regions.setAnchors(options.anchors)
+	}
+	s := &search{
+		regions:    regions,
+		candidates: make([][]int, len(regions.value)),
+		limit:      limit,
+		options:    options,
+	}
+	for _, cell := range fieldState.field.GetAllCells() {
+		i := regions.index(cell)
+		value := fieldState.GetState(cell)
+		if value == 0 {
+			s.free = append(s.free, i)
+			s.candidates[i] = possibleValues[cell]
+			continue
+		}
+		if !regions.assign(i, value) {
+			s.failed = true
+		}
+	}
+	// Givens placed early saw the later ones as empty cells, so whether
+	// their regions can still grow is only known now.
+	for i, value := range regions.value {
+		if value != 0 && !s.failed && !regions.hasRoom(i) {
+			s.failed = true
+		}
+	}
+	return s
+}
+
+// run explores the search tree until limit solutions are found or the tree
+// is exhausted, and returns the number of solutions seen.
+func (s *search) run() int {
+	if !s.failed {
+		s.backtrack()
+	}
+	return s.solutions
+}
+
+// closed reports whether the cancel channel of a search has been closed.
+func closed(cancel <-chan struct{}) bool {
+	if cancel == nil {
+		return false
+	}
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// move is a single cell assignment.
+type move struct {
+	cell, value int
+}
+
+// branches returns the alternatives for the next level of the tree in the
+// order they are tried. Each alternative is a list of moves applied
+// together; an empty result means the node is a dead end.
+func (s *search) branches() [][]move {
+	if s.options.Mode == ModeRegions {
+		if alternatives, ok := s.regionBranches(); ok {
+			return alternatives
+		}
+	}
+	i, values := s.chooseCell()
+	alternatives := make([][]move, len(values))
+	for k, value := range values {
+		alternatives[k] = []move{{i, value}}
+	}
+	return alternatives
+}
+
+// apply performs the moves of one alternative and reports whether all of
+// them were consistent. Callers undo to a mark and restore the path either
+// way.
+func (s *search) apply(moves []move) bool {
+	for _, m := range moves {
+		s.path = pathStep(s.path, m)
+	}
+	for _, m := range moves {
+		if !s.regions.assign(m.cell, m.value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *search) complete() bool {
+	return s.regions.filled == len(s.regions.value)
+}
+
+// backtrack descends one level per alternative returned by branches. It
+// returns true once the limit is reached.
+func (s *search) backtrack() bool {
+	s.nodes++
+	if s.options.Progress != nil && s.nodes%progressInterval == 0 {
+		s.options.Progress(s.nodes, s.depth)
+	}
+	if s.cancelled || closed(s.options.Cancel) {
+		s.cancelled = true
+		return true
+	}
+	if s.shared != nil && s.shared.stopped(s.task) {
+		return true
+	}
+	if s.complete() {
+		s.solutions++
+		if s.solution == nil {
+			s.solution = append([]int(nil), s.regions.value...)
+		}
+		if s.shared != nil {
+			s.shared.record(s.task)
+			return s.shared.stopped(s.task)
+		}
+		return s.solutions >= s.limit
+	}
+	for _, alternative := range s.branches() {
+		mark, path := s.regions.mark(), s.path
+		ok := s.apply(alternative)
+		s.depth++
+		s.trace(TraceAssign, alternative)
+		done := ok && s.backtrack()
+		if !done {
+			s.trace(TraceUndo, alternative)
+		}
+		s.depth--
+		s.regions.undo(mark)
+		s.path = path
+		if done {
+			return true
+		}
+	}
+	return false
+}
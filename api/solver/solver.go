@@ -31,6 +31,20 @@ func checkSize(size int) error {
 	return nil
 }
 
+func checkMatrix(matrix [][]int) error {
+	for x, row := range matrix {
+		if len(row) != len(matrix) {
+			return fmt.Errorf("row %d has %d cells, expected %d", x, len(row), len(matrix))
+		}
+		for y, value := range row {
+			if value < 0 {
+				return fmt.Errorf("negative value at %d,%d", x, y)
+			}
+		}
+	}
+	return nil
+}
+
 func (f *Field) Size() int {
 	return f.size
 }
@@ -79,6 +93,9 @@ func FromListToState(matrix [][]int) (map[string]interface{}, error) {
 	if err != nil {
 		return map[string]interface{}{"error": err.Error()}, err
 	}
+	if err := checkMatrix(matrix); err != nil {
+		return map[string]interface{}{"error": err.Error()}, err
+	}
 	state := NewFieldState(field)
 
 	for x := 0; x < size; x++ {
@@ -131,328 +148,254 @@ func (fs *FieldState) GetInvolved(cell Cell) []Cell {
 	return involved
 }
 
-type CellsGroup struct {
-	value                  int
-	initialCells           []Cell
-	possibleCells          []Cell
-	possibleConnectionCells []Cell
-}
-
-func NewCellsGroup(value int, initialCells []Cell) *CellsGroup {
-	return &CellsGroup{
-		value:        value,
-		initialCells: initialCells,
+// IsSolved reports whether every cell is filled and no region breaks the
+// rules.
+func (fs *FieldState) IsSolved() bool {
+	for _, cell := range fs.field.GetAllCells() {
+		if fs.GetState(cell) == 0 {
+			return false
+		}
 	}
+	return len(fs.Conflicts()) == 0
 }
 
-func (cg *CellsGroup) GetValue() int {
-	return cg.value
-}
-
-func (cg *CellsGroup) GetPossibleLength() int {
-	return len(cg.initialCells) + len(cg.possibleCells) + len(cg.possibleConnectionCells)
-}
-
-func (cg *CellsGroup) AddPossibleCell(cell Cell) {
-	cg.possibleCells = append(cg.possibleCells, cell)
-}
-
-func (cg *CellsGroup) AddConnection(cell Cell) {
-	cg.possibleConnectionCells = append(cg.possibleConnectionCells, cell)
+// Conflicts returns the cells of regions that break the rules: regions
+// larger than their number, and regions smaller than their number with no
+// empty cell left to grow into. The cells come in no particular order.
+func (fs *FieldState) Conflicts() []Cell {
+	var conflicts []Cell
+	seen := make(map[Cell]struct{})
+	for _, cell := range fs.field.GetAllCells() {
+		value := fs.GetState(cell)
+		if _, ok := seen[cell]; ok || value == 0 {
+			continue
+		}
+		region := fs.GetInvolved(cell)
+		open := false
+		for _, c := range region {
+			seen[c] = struct{}{}
+			for neighbour := range fs.field.GetNeighbourCells(c) {
+				if fs.GetState(neighbour) == 0 {
+					open = true
+				}
+			}
+		}
+		if len(region) > value || len(region) < value && !open {
+			conflicts = append(conflicts, region...)
+		}
+	}
+	return conflicts
 }
 
 type PuzzleSolver struct {
 	possibleValues map[Cell][]int
-	involved       []Cell
-	unfilledGroups map[Cell]*CellsGroup
 	fieldState     *FieldState
 	stateChanged   bool
-}
+	options        SolveOptions
+}
+
+// SolveOptions tunes how PuzzleSolver searches. The zero value is the plain
+// sequential solver.
+type SolveOptions struct {
+	// Workers is the number of goroutines exploring the search tree.
+	// Values below 2 search on the calling goroutine.
+	Workers int
+	// Mode selects cell-by-cell or region-by-region branching.
+	Mode SearchMode
+	// CellOrder and ValueOrder pick the branching heuristics.
+	CellOrder  CellOrder
+	ValueOrder ValueOrder
+	// Seed seeds ValueRandom.
+	Seed int64
+	// Cancel stops the search when it is closed; Solve and CountSolutions
+	// then fail with ErrCancelled.
+	Cancel <-chan struct{}
+	// Progress, when set, is called every progressInterval search nodes
+	// with the nodes explored so far and the current depth. It runs on the
+	// searching goroutine; with Workers above 1 every branch reports its
+	// own counts concurrently. ModeExactCover does not report.
+	Progress func(nodes, depth int)
+	// Trace, when set, is called with every move the search tries and
+	// takes back, on the searching goroutine. A move that breaks a region
+	// is reported and undone right away; once the search stops, the moves
+	// on the way to the final board are not undone. ModeExactCover does
+	// not trace, and with Workers above 1 the branches trace concurrently.
+	Trace func(TraceEvent)
+
+	// anchors, when set, are cells every region must contain one of. The
+	// template generator uses them to only draw solutions whose regions
+	// all hold a clue.
+	anchors []Cell
+}
+
+// ErrCancelled is returned when a search is stopped through
+// SolveOptions.Cancel.
+var ErrCancelled = errors.New("search cancelled")
 
 func NewPuzzleSolver(fieldState *FieldState) *PuzzleSolver {
 	return &PuzzleSolver{
 		fieldState:     fieldState,
 		stateChanged:   true,
-		unfilledGroups: make(map[Cell]*CellsGroup),
 		possibleValues: make(map[Cell][]int),
 	}
 }
 
+func (ps *PuzzleSolver) SetOptions(options SolveOptions) {
+	ps.options = options
+}
+
 func (ps *PuzzleSolver) Solve() (map[string]interface{}, error) {
 	if err := ps.refreshState(); err != nil {
 		return map[string]interface{}{"error": err.Error()}, err
 	}
-	ps.tryFillEmptyCells()
-	if ps.checkForZeros() {
+	if err := ps.tryFillEmptyCells(); err != nil {
+		return map[string]interface{}{"error": err.Error()}, err
+	}
+	if !ps.fieldState.IsSolved() {
 		return map[string]interface{}{"error": "Puzzle is unsolvable"}, errors.New("puzzle is unsolvable")
 	}
 	return map[string]interface{}{"solved_puzzle": ps.fieldState.ToList()}, nil
 }
 
-func (ps *PuzzleSolver) refreshState() error {
-	ps.findUnfilledGroups()
-	for _, cell := range ps.fieldState.field.GetAllCells() {
-		if ps.fieldState.GetState(cell) != 0 {
-			if _, ok := ps.unfilledGroups[cell]; ok {
-				ps.findPossibleValues(cell)
-			}
-		}
+// CountSolutions returns the number of solutions of the current state, but
+// stops counting at limit. The state itself is left untouched.
+func (ps *PuzzleSolver) CountSolutions(limit int) (int, error) {
+	if err := ps.refreshState(); err != nil {
+		return 0, err
 	}
+	count, _, err := ps.runSearch(limit)
+	return count, err
+}
 
-	var emptyCells []Cell
-	for _, cell := range ps.fieldState.field.GetAllCells() {
-		if ps.fieldState.GetState(cell) == 0 {
-			emptyCells = append(emptyCells, cell)
-		}
+func (ps *PuzzleSolver) refreshState() error {
+	if err := ps.checkGivenRegions(); err != nil {
+		return err
 	}
+	ps.possibleValues = make(map[Cell][]int)
 
-	involved := make(map[Cell]struct{})
-	for _, cell := range emptyCells {
-		allNonOne := true
-		for neighbor := range ps.fieldState.field.GetNeighbourCells(cell) {
-			if ps.fieldState.GetState(neighbor) == 1 {
-				allNonOne = false
-				break
-			}
-		}
-		if allNonOne {
-			ps.addPossibleValue(cell, 1)
-		}
-
-		if _, ok := involved[cell]; !ok {
-			emptyGroup := ps.fieldState.GetInvolved(cell)
-			for _, e := range emptyGroup {
-				involved[e] = struct{}{}
-			}
-			ps.findAdditionalValues(emptyGroup)
+	cells := ps.fieldState.field.GetAllCells()
+	counts := make(map[int]int)
+	for _, cell := range cells {
+		counts[ps.fieldState.GetState(cell)]++
+	}
+	// Values no given holds can only spread over the blank areas, so those
+	// are flooded once; a value above the largest of them needs a given.
+	blank := ps.findReachableAreas(0)
+	largest := 0
+	for _, area := range blank {
+		if len(area) > largest {
+			largest = len(area)
 		}
 	}
-
-	ps.stateChanged = true
-	return nil
-}
-
-func (ps *PuzzleSolver) findAdditionalValues(emptyGroup []Cell) {
-	for value := 2; value <= min(len(emptyGroup)+1, 10); value++ {
-		involvedForValue := make(map[Cell]struct{})
-		for _, cell := range emptyGroup {
-			involvedForValue[cell] = struct{}{}
+	for value := 1; value <= len(cells); value++ {
+		if closed(ps.options.Cancel) {
+			return ErrCancelled
 		}
-		notPossibleCells := make(map[Cell]struct{})
-
-		for cell := range involvedForValue {
-			for neighbor := range ps.fieldState.field.GetNeighbourCells(cell) {
-				if ps.fieldState.GetState(neighbor) == value {
-					notPossibleCells[cell] = struct{}{}
-				}
-			}
+		if counts[0]+counts[value] < value || (counts[value] == 0 && value > largest) {
+			continue
 		}
-		for cell := range notPossibleCells {
-			delete(involvedForValue, cell)
+		areas := blank
+		if counts[value] > 0 {
+			areas = ps.findReachableAreas(value)
 		}
-
-		if value <= len(involvedForValue) {
-			for cell := range involvedForValue {
-				ps.addPossibleValue(cell, value)
+		for _, area := range areas {
+			if len(area) < value {
+				continue
 			}
-		}
-	}
-}
-
-func (ps *PuzzleSolver) findUnfilledGroups() {
-	ps.unfilledGroups = make(map[Cell]*CellsGroup)
-	ps.involved = nil
-	ps.possibleValues = make(map[Cell][]int)
-
-	for _, cell := range ps.fieldState.field.GetAllCells() {
-		if ps.fieldState.GetState(cell) != 0 {
-			if _, ok := ps.involved[cell]; !ok {
-				initialCells := ps.fieldState.GetInvolved(cell)
-				ps.involved = append(ps.involved, initialCells...)
-				value := ps.fieldState.GetState(cell)
-
-				if len(initialCells) < value {
-					newGroup := NewCellsGroup(value, initialCells)
-					for _, c := range initialCells {
-						ps.unfilledGroups[c] = newGroup
-					}
-				}
-
-				if len(initialCells) > value {
-					panic("wrong group size")
+			for _, cell := range area {
+				if ps.fieldState.GetState(cell) == 0 {
+					ps.possibleValues[cell] = append(ps.possibleValues[cell], value)
 				}
 			}
 		}
 	}
+
+	ps.stateChanged = true
+	return nil
 }
 
-func (ps *PuzzleSolver) findPossibleValues(cell Cell) {
-	var nextCells []Cell
-	nextCells = append(nextCells, cell)
-	value := ps.fieldState.GetState(cell)
-	var previousCells []Cell
-	group := ps.unfilledGroups[cell]
-
-	for len(nextCells) > 0 {
-		currentCell := nextCells[len(nextCells)-1]
-		nextCells = nextCells[:len(nextCells)-1]
-		previousCells = append(previousCells, currentCell)
-		currentLength := len(previousCells)
-
-		wayLength := currentLength + len(group.initialCells)
-
-		if wayLength < value && currentCell != cell {
-			ps.addPossibleValue(currentCell, value)
-			group.AddPossibleCell(currentCell)
-		} else if wayLength == value {
-			ps.addPossibleValue(currentCell, value)
-			group.AddPossibleCell(currentCell)
+// findReachableAreas splits the cells that are empty or already hold value
+// into connected areas. A region of that value can only live inside an area
+// that has at least value cells.
+func (ps *PuzzleSolver) findReachableAreas(value int) [][]Cell {
+	var areas [][]Cell
+	checked := make(map[Cell]struct{})
+	for _, cell := range ps.fieldState.field.GetAllCells() {
+		if _, ok := checked[cell]; ok {
 			continue
 		}
-
-		freeNeighbours := make(map[Cell]struct{})
-		for neighbor := range ps.fieldState.field.GetNeighbourCells(currentCell) {
-			if ps.fieldState.GetState(neighbor) == 0 {
-				freeNeighbours[neighbor] = struct{}{}
-			}
+		if state := ps.fieldState.GetState(cell); state != 0 && state != value {
+			continue
 		}
-
-		for neighbor := range freeNeighbours {
-			if ps.connectionCellsFound(cell, neighbor, group) {
-				continue
+		area := []Cell{cell}
+		notChecked := []Cell{cell}
+		checked[cell] = struct{}{}
+		for len(notChecked) > 0 {
+			current := notChecked[len(notChecked)-1]
+			notChecked = notChecked[:len(notChecked)-1]
+			for neighbor := range ps.fieldState.field.GetNeighbourCells(current) {
+				if _, ok := checked[neighbor]; ok {
+					continue
+				}
+				if state := ps.fieldState.GetState(neighbor); state != 0 && state != value {
+					continue
+				}
+				checked[neighbor] = struct{}{}
+				area = append(area, neighbor)
+				notChecked = append(notChecked, neighbor)
 			}
-			nextCells = append(nextCells, neighbor)
-		}
-	}
-}
-
-func (ps *PuzzleSolver) connectionCellsFound(neighbor Cell, cell Cell, group *CellsGroup) bool {
-	value := group.GetValue()
-
-	if ps.fieldState.GetState(neighbor) == value && !contains(group.initialCells, neighbor) && !contains(group.possibleCells, neighbor) {
-		ps.fieldState.SetState(cell, value)
-		intersectionLength := len(ps.fieldState.GetInvolved(cell))
-		ps.fieldState.SetState(cell, 0)
-
-		if intersectionLength <= value {
-			group.AddConnection(cell)
-			ps.addPossibleValue(cell, value)
-		}
-		return true
-	}
-	return false
-}
-
-func (ps *PuzzleSolver) addPossibleValue(cell Cell, value int) {
-	for _, n := range ps.fieldState.field.GetNeighbourCells(cell) {
-		if nValue := ps.fieldState.GetState(n); nValue != 0 && abs(nValue-value) == 1 {
-			return
 		}
+		areas = append(areas, area)
 	}
-	ps.possibleValues[cell] = append(ps.possibleValues[cell], value)
+	return areas
 }
 
-func (ps *PuzzleSolver) tryFillEmptyCells() {
-	var freeCells []Cell
+// checkGivenRegions fails when the givens already form a region larger
+// than its value.
+func (ps *PuzzleSolver) checkGivenRegions() error {
+	checked := make(map[Cell]struct{})
 	for _, cell := range ps.fieldState.field.GetAllCells() {
-		if ps.fieldState.GetState(cell) == 0 {
-			freeCells = append(freeCells, cell)
-		}
-	}
-	possibleValues := ps.possibleValues
-	backtrack(freeCells, possibleValues, ps.fieldState)
-}
-
-func backtrack(freeCells []Cell, possibleValues map[Cell][]int, fieldState *FieldState) bool {
-	if len(freeCells) == 0 {
-		return true
-	}
-	cell := freeCells[len(freeCells)-1]
-	freeCells = freeCells[:len(freeCells)-1]
-	for _, value := range possibleValues[cell] {
-		fieldState.SetState(cell, value)
-		if checkGroupSize(fieldState) && backtrack(freeCells, possibleValues, fieldState) {
-			return true
-		}
-		fieldState.SetState(cell, 0)
-	}
-	return false
-}
-
-func checkGroupSize(fieldState *FieldState) bool {
-	groups := make(map[*CellsGroup]struct{})
-	for _, cell := range fieldState.field.GetAllCells() {
-		value := fieldState.GetState(cell)
-		if value == 0 {
+		value := ps.fieldState.GetState(cell)
+		if _, ok := checked[cell]; ok || value == 0 {
 			continue
 		}
-		group := fieldState.GetInvolved(cell)
-		if len(group) != value {
-			return false
+		region := ps.fieldState.GetInvolved(cell)
+		for _, c := range region {
+			checked[c] = struct{}{}
 		}
-		groups[NewCellsGroup(value, group)] = struct{}{}
-	}
-	for group := range groups {
-		if group.GetPossibleLength() < group.GetValue() && len(group.possibleConnectionCells) == 0 {
-			return false
+		if len(region) > value {
+			return fmt.Errorf("region of %d at %d,%d has %d cells", value, cell.X, cell.Y, len(region))
 		}
 	}
-	return true
+	return nil
 }
 
-func (ps *PuzzleSolver) checkForZeros() bool {
-	for _, row := range ps.fieldState.ToList() {
-		for _, val := range row {
-			if val == 0 {
-				return true
-			}
-		}
+func (ps *PuzzleSolver) tryFillEmptyCells() error {
+	count, solution, err := ps.runSearch(1)
+	if err != nil {
+		return err
 	}
-	return false
-}
-
-// Helper functions
-func contains(cells []Cell, cell Cell) bool {
-	for _, c := range cells {
-		if c == cell {
-			return true
+	if count > 0 {
+		for i, value := range solution {
+			ps.fieldState.SetState(Cell{i / ps.fieldState.field.Size(), i % ps.fieldState.field.Size()}, value)
 		}
 	}
-	return false
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
-func abs(a int) int {
-	if a < 0 {
-		return -a
-	}
-	return a
+	return nil
 }
 
-func main() {
-	// Test code for the puzzle solver would go here.
-	matrix := [][]int{
-		{0, 0, 0},
-		{0, 0, 0},
-		{0, 0, 0},
+// runSearch explores the state until limit solutions are found and returns
+// how many were seen together with the first one in search order.
+func (ps *PuzzleSolver) runSearch(limit int) (int, []int, error) {
+	if ps.options.Mode == ModeExactCover {
+		return solveExactCover(ps.fieldState, limit, ps.options.Cancel)
 	}
-	result, err := FromListToState(matrix)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return
+	if ps.options.Workers > 1 {
+		return runParallel(ps.fieldState, ps.possibleValues, limit, ps.options)
 	}
-	fieldState := result["state"].(*FieldState)
-	solver := NewPuzzleSolver(fieldState)
-	solution, err := solver.Solve()
-	if err != nil {
-		fmt.Println("Error:", err)
-		return
+	s := newSearch(ps.fieldState, ps.possibleValues, limit, ps.options)
+	count := s.run()
+	if s.cancelled {
+		return 0, nil, ErrCancelled
 	}
-	fmt.Println("Solved Puzzle:", solution["solved_puzzle"])
+	return count, s.solution, nil
 }
@@ -0,0 +1,160 @@
+package solver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Symmetry is one of the eight symmetries of a square board.
+type Symmetry int
+
+const (
+	Identity Symmetry = iota
+	// Rotate90 turns the board a quarter turn clockwise.
+	Rotate90
+	Rotate180
+	Rotate270
+	// FlipHorizontal mirrors the board top to bottom.
+	FlipHorizontal
+	// FlipVertical mirrors the board left to right.
+	FlipVertical
+	// Transpose mirrors the board in its main diagonal.
+	Transpose
+	// AntiTranspose mirrors the board in its other diagonal.
+	AntiTranspose
+)
+
+// Symmetries lists all eight symmetries, Identity first.
+var Symmetries = []Symmetry{Identity, Rotate90, Rotate180, Rotate270, FlipHorizontal, FlipVertical, Transpose, AntiTranspose}
+
+// Apply returns where cell ends up on a board of the given size.
+func (s Symmetry) Apply(cell Cell, size int) Cell {
+	last := size - 1
+	switch s {
+	case Rotate90:
+		return Cell{cell.Y, last - cell.X}
+	case Rotate180:
+		return Cell{last - cell.X, last - cell.Y}
+	case Rotate270:
+		return Cell{last - cell.Y, cell.X}
+	case FlipHorizontal:
+		return Cell{last - cell.X, cell.Y}
+	case FlipVertical:
+		return Cell{cell.X, last - cell.Y}
+	case Transpose:
+		return Cell{cell.Y, cell.X}
+	case AntiTranspose:
+		return Cell{last - cell.Y, last - cell.X}
+	}
+	return cell
+}
+
+// Transform returns a copy of the state with every cell moved by s.
+func (fs *FieldState) Transform(s Symmetry) *FieldState {
+	size := fs.field.Size()
+	result := NewFieldState(&Field{size: size, neighborCache: make(map[Cell]map[Cell]struct{})})
+	for cell, value := range fs.state {
+		result.state[s.Apply(cell, size)] = value
+	}
+	return result
+}
+
+// Canonical returns the representative of the state among its eight
+// symmetric copies, the one whose rows read smallest, together with the
+// symmetry that produces it. Fillomino numbers are region sizes, so unlike
+// puzzles with interchangeable symbols there is no value relabeling to
+// factor out.
+func (fs *FieldState) Canonical() (*FieldState, Symmetry) {
+	best, bestSymmetry := fs, Identity
+	bestList := fs.ToList()
+	for _, s := range Symmetries[1:] {
+		candidate := fs.Transform(s)
+		list := candidate.ToList()
+		if lessGrid(list, bestList) {
+			best, bestSymmetry, bestList = candidate, s, list
+		}
+	}
+	return best, bestSymmetry
+}
+
+func lessGrid(a, b [][]int) bool {
+	for x := range a {
+		for y := range a[x] {
+			if a[x][y] != b[x][y] {
+				return a[x][y] < b[x][y]
+			}
+		}
+	}
+	return false
+}
+
+// Fingerprint is a hex SHA-256 of the canonical form. Rotated and mirrored
+// copies of a puzzle share it.
+func (fs *FieldState) Fingerprint() string {
+	canonical, _ := fs.Canonical()
+	hash := sha256.New()
+	hash.Write([]byte(strconv.Itoa(canonical.field.Size())))
+	for _, row := range canonical.ToList() {
+		for _, value := range row {
+			hash.Write([]byte("," + strconv.Itoa(value)))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ClueSymmetry is the symmetry of a clue layout: a clue in one cell implies
+// clues in every cell the symmetry's group maps it to.
+type ClueSymmetry int
+
+const (
+	SymmetryNone ClueSymmetry = iota
+	SymmetryRotate180
+	SymmetryRotate90
+	// SymmetryHorizontal mirrors the layout top to bottom.
+	SymmetryHorizontal
+	// SymmetryVertical mirrors the layout left to right.
+	SymmetryVertical
+	// SymmetryDiagonal mirrors the layout in the main diagonal.
+	SymmetryDiagonal
+)
+
+func (cs ClueSymmetry) group() []Symmetry {
+	switch cs {
+	case SymmetryRotate180:
+		return []Symmetry{Identity, Rotate180}
+	case SymmetryRotate90:
+		return []Symmetry{Identity, Rotate90, Rotate180, Rotate270}
+	case SymmetryHorizontal:
+		return []Symmetry{Identity, FlipHorizontal}
+	case SymmetryVertical:
+		return []Symmetry{Identity, FlipVertical}
+	case SymmetryDiagonal:
+		return []Symmetry{Identity, Transpose}
+	}
+	return []Symmetry{Identity}
+}
+
+// orbits splits the cells of a board into the sets the symmetry's group
+// maps onto each other, in row order of their first cell.
+func (cs ClueSymmetry) orbits(size int) [][]Cell {
+	seen := make(map[Cell]bool)
+	var orbits [][]Cell
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			if seen[Cell{x, y}] {
+				continue
+			}
+			var orbit []Cell
+			for _, s := range cs.group() {
+				cell := s.Apply(Cell{x, y}, size)
+				if !seen[cell] {
+					seen[cell] = true
+					orbit = append(orbit, cell)
+				}
+			}
+			orbits = append(orbits, orbit)
+		}
+	}
+	return orbits
+}
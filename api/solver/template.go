@@ -0,0 +1,148 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Cells of a template. Any positive value is a clue with that value fixed.
+const (
+	// TemplateEmpty marks a cell left empty in the puzzle.
+	TemplateEmpty = 0
+	// TemplateClue marks a clue whose value the generator picks.
+	TemplateClue = -1
+)
+
+// ErrTemplateFailed is returned when no solution drawn for a template gave
+// a unique puzzle.
+var ErrTemplateFailed = errors.New("no unique puzzle found for the template")
+
+// Random searches either finish quickly or wander for a long time, so a
+// draw that takes longer than drawTimeout is restarted with another seed.
+// checkTimeout bounds the uniqueness check of a drawn puzzle.
+const (
+	drawTimeout  = 100 * time.Millisecond
+	checkTimeout = time.Second
+)
+
+// GenerateFromTemplate makes a puzzle with clues exactly where the template
+// has them. It draws random solutions that agree with the fixed values and
+// keeps the first one whose values at the template's clue cells give a
+// unique puzzle, giving up after attempts draws.
+func (pg *PuzzleGenerator) GenerateFromTemplate(template [][]int, attempts int) ([][]int, error) {
+	if err := pg.checkTemplate(template); err != nil {
+		return nil, err
+	}
+	fixed := make([][]int, pg.size)
+	for x := range fixed {
+		fixed[x] = make([]int, pg.size)
+		for y, value := range template[x] {
+			if value > 0 {
+				fixed[x][y] = value
+			}
+		}
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for attempt := 0; attempt < attempts; attempt++ {
+		pg.report(attempt, attempts)
+		if closed(pg.cancel) {
+			return nil, ErrCancelled
+		}
+		result, err := FromListToState(fixed)
+		if err != nil {
+			return nil, err
+		}
+		puzzle, err := pg.drawFromTemplate(result["state"].(*FieldState), template, random.Int63())
+		if err == ErrCancelled {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if puzzle != nil {
+			pg.report(attempts, attempts)
+			return puzzle, nil
+		}
+	}
+	return nil, ErrTemplateFailed
+}
+
+// drawFromTemplate solves the fixed values with a random seed and returns
+// the solution's values at the template's clue cells, or nil if they do not
+// give a unique puzzle. Only solutions whose regions all hold a clue cell
+// are drawn, as a region without one can nearly always be drawn another
+// way.
+func (pg *PuzzleGenerator) drawFromTemplate(fixed *FieldState, template [][]int, seed int64) ([][]int, error) {
+	var clues []Cell
+	for _, cell := range fixed.field.GetAllCells() {
+		if template[cell.X][cell.Y] != TemplateEmpty {
+			clues = append(clues, cell)
+		}
+	}
+	ps := NewPuzzleSolver(fixed)
+	ps.SetOptions(SolveOptions{ValueOrder: ValueRandom, Seed: seed, Cancel: pg.timeout(drawTimeout), anchors: clues})
+	solution, err := ps.Solve()
+	if err == ErrCancelled {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no solution fits the template: %v", err)
+	}
+
+	puzzle := solution["solved_puzzle"].([][]int)
+	for x := range puzzle {
+		for y := range puzzle[x] {
+			if template[x][y] == TemplateEmpty {
+				puzzle[x][y] = 0
+			}
+		}
+	}
+	ok, err := unique(puzzle, SolveOptions{Cancel: pg.timeout(checkTimeout)})
+	if err != nil || !ok {
+		return nil, err
+	}
+	return puzzle, nil
+}
+
+// timeout returns a channel that is closed after d, or earlier when the
+// generator is cancelled.
+func (pg *PuzzleGenerator) timeout(d time.Duration) <-chan struct{} {
+	stop := make(chan struct{})
+	go func() {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-pg.cancel:
+		}
+		close(stop)
+	}()
+	return stop
+}
+
+func (pg *PuzzleGenerator) checkTemplate(template [][]int) error {
+	if len(template) != pg.size {
+		return fmt.Errorf("template has %d rows, expected %d", len(template), pg.size)
+	}
+	clues := 0
+	for x, row := range template {
+		if len(row) != pg.size {
+			return fmt.Errorf("template row %d has %d cells, expected %d", x, len(row), pg.size)
+		}
+		for y, value := range row {
+			if value < TemplateClue || value > pg.size*pg.size {
+				return fmt.Errorf("bad template value %d at %d,%d", value, x, y)
+			}
+			if value != TemplateEmpty {
+				clues++
+			}
+		}
+	}
+	if clues == 0 {
+		return errors.New("template has no clues")
+	}
+	return nil
+}
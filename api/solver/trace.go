@@ -0,0 +1,41 @@
+package solver
+
+// TraceKind tells the steps reported to SolveOptions.Trace apart.
+type TraceKind int
+
+const (
+	// TraceAssign is a value tried on an empty cell.
+	TraceAssign TraceKind = iota
+	// TraceUndo is the cell emptied again when the search backs out.
+	TraceUndo
+)
+
+func (k TraceKind) String() string {
+	if k == TraceUndo {
+		return "undo"
+	}
+	return "assign"
+}
+
+// TraceEvent is one step of the search. Depth is the level of the tree the
+// move belongs to, 1 for the first branch below the givens.
+type TraceEvent struct {
+	Kind  TraceKind
+	Cell  Cell
+	Value int
+	Depth int
+}
+
+// trace reports the moves of one alternative, undone in reverse order.
+func (s *search) trace(kind TraceKind, moves []move) {
+	if s.options.Trace == nil {
+		return
+	}
+	for k := range moves {
+		m := moves[k]
+		if kind == TraceUndo {
+			m = moves[len(moves)-1-k]
+		}
+		s.options.Trace(TraceEvent{Kind: kind, Cell: s.regions.cell(m.cell), Value: m.value, Depth: s.depth})
+	}
+}
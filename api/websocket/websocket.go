@@ -0,0 +1,362 @@
+// Package websocket implements the server side of the WebSocket protocol
+// (RFC 6455) as far as the API needs it: the opening handshake, text
+// messages both ways, pings both ways and the closing handshake.
+// Extensions and subprotocols are not supported.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcodes of the frames the package handles.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close codes sent with Close.
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseTooBig        = 1009
+)
+
+// MaxMessageSize bounds the messages a client may send.
+const MaxMessageSize = 64 << 10
+
+// maxControlSize bounds the payload of close, ping and pong frames.
+const maxControlSize = 125
+
+// DefaultReadTimeout is Options.ReadTimeout when none is given.
+const DefaultReadTimeout = time.Minute
+
+// writeTimeout bounds every frame sent, so a client that stopped reading
+// cannot hold a writer.
+const writeTimeout = 10 * time.Second
+
+// acceptGUID is the key suffix fixed by RFC 6455.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	// ErrClosed is returned by ReadMessage once the client closed the
+	// connection.
+	ErrClosed = errors.New("websocket: connection closed")
+	// ErrTooBig is returned for client messages over MaxMessageSize.
+	ErrTooBig = errors.New("websocket: message too big")
+)
+
+// Conn is an upgraded connection. Writes may come from several goroutines;
+// reads must come from one.
+type Conn struct {
+	conn    net.Conn
+	in      *bufio.Reader
+	timeout time.Duration
+	done    chan struct{}
+
+	// message holds the frames of a fragmented message read so far.
+	message    []byte
+	fragmented bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Options configures Upgrade.
+type Options struct {
+	// Origins lists the values of the Origin header allowed to open a
+	// connection, such as "https://example.com". When empty, only pages
+	// served from the host the request was sent to may. Requests without
+	// an Origin header do not come from a browser and are always allowed.
+	Origins []string
+	// ReadTimeout is how long a client may stay silent before the
+	// connection is dropped; a frame that takes longer to arrive drops it
+	// as well. The server pings the client twice per timeout, so a client
+	// that answers pings may stay idle.
+	ReadTimeout time.Duration
+}
+
+// allows reports whether the Origin of r may open a connection. Browsers
+// send cookies and credentials along with WebSocket handshakes from any
+// page, so the origin is all that tells a foreign page apart.
+func (o Options) allows(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(o.Origins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	for _, allowed := range o.Origins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrade performs the opening handshake on a request and takes over its
+// connection. On failure an error response has already been written.
+func Upgrade(w http.ResponseWriter, r *http.Request, options Options) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	if !options.allows(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, errors.New("websocket: origin not allowed")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: connection cannot be hijacked")
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	_, err = fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", AcceptKey(key))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	timeout := options.ReadTimeout
+	if timeout <= 0 {
+		timeout = DefaultReadTimeout
+	}
+	c := &Conn{conn: conn, in: buffered.Reader, timeout: timeout, done: make(chan struct{})}
+	go c.keepAlive()
+	return c, nil
+}
+
+// keepAlive pings the client until the connection is closed, so a client
+// that is only listening still answers within the read timeout.
+func (c *Conn) keepAlive() {
+	ticker := time.NewTicker(c.timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.writeFrame(opPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept value answering a client key.
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteText sends a text message.
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return writeFrame(c.conn, opcode, payload, nil)
+}
+
+// writeFrame writes a single final frame, masked when mask is set.
+func writeFrame(w io.Writer, opcode byte, payload []byte, mask []byte) error {
+	header := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	if mask != nil {
+		header[1] |= 0x80
+		header = append(header, mask...)
+		masked := make([]byte, len(payload))
+		for i, b := range payload {
+			masked[i] = b ^ mask[i%4]
+		}
+		payload = masked
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// ReadMessage returns the next text or binary message from the client,
+// answering pings on the way. It returns ErrClosed once the client starts
+// the closing handshake, after answering it. Any other error closes the
+// connection.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			c.Close(CloseGoingAway)
+			return nil, err
+		}
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			c.closeWith(payload)
+			return nil, ErrClosed
+		case opText, opBinary, opContinuation:
+			// A continuation goes on a message that was started, and a
+			// new message waits for the last one to end.
+			if (opcode == opContinuation) != c.fragmented {
+				c.Close(CloseProtocolError)
+				return nil, errors.New("websocket: unexpected continuation frame")
+			}
+			if len(c.message)+len(payload) > MaxMessageSize {
+				c.Close(CloseTooBig)
+				return nil, ErrTooBig
+			}
+			c.message = append(c.message, payload...)
+			c.fragmented = !fin
+			if fin {
+				message := c.message
+				c.message = nil
+				return message, nil
+			}
+		default:
+			c.Close(CloseProtocolError)
+			return nil, fmt.Errorf("websocket: unknown opcode %d", opcode)
+		}
+	}
+}
+
+// readFrame reads one frame, which must arrive whole within the read
+// timeout.
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	if err = c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return
+	}
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(c.in, header); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		c.Close(CloseProtocolError)
+		return false, 0, nil, errors.New("websocket: reserved bits set without an extension")
+	}
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err = io.ReadFull(c.in, extended); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err = io.ReadFull(c.in, extended); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if !masked {
+		c.Close(CloseProtocolError)
+		return false, 0, nil, errors.New("websocket: unmasked client frame")
+	}
+	if opcode >= opClose && (!fin || length > maxControlSize) {
+		c.Close(CloseProtocolError)
+		return false, 0, nil, errors.New("websocket: control frame fragmented or over 125 bytes")
+	}
+	if length > MaxMessageSize {
+		c.Close(CloseTooBig)
+		return false, 0, nil, ErrTooBig
+	}
+	mask := make([]byte, 4)
+	if _, err = io.ReadFull(c.in, mask); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.in, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// Close sends a close frame with the given code and closes the connection.
+func (c *Conn) Close(code int) error {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return c.closeWith(payload)
+}
+
+func (c *Conn) closeWith(payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	writeFrame(c.conn, opClose, payload, nil)
+	return c.conn.Close()
+}
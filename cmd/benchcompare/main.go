@@ -0,0 +1,201 @@
+// Command benchcompare runs the solver benchmarks at two commits and prints
+// how the timings changed:
+//
+//	benchcompare [-count 5] [-bench .] [-pkg ./tests/solvertests/] <old> <new>
+//
+// Each commit is checked out into a temporary git worktree, so the working
+// tree is left alone. With -files the two arguments are saved "go test
+// -bench" outputs instead of commits.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	count := flag.Int("count", 5, "runs of every benchmark")
+	bench := flag.String("bench", ".", "benchmarks to run")
+	pkg := flag.String("pkg", "./tests/solvertests/", "package holding the benchmarks")
+	files := flag.Bool("files", false, "compare two saved benchmark outputs")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: benchcompare [flags] <old commit> <new commit>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var results [2]map[string][]float64
+	for i, arg := range flag.Args() {
+		var output string
+		var err error
+		if *files {
+			var data []byte
+			data, err = ioutil.ReadFile(arg)
+			output = string(data)
+		} else {
+			output, err = runAt(arg, *pkg, *bench, *count)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "benchcompare:", err)
+			os.Exit(1)
+		}
+		results[i] = parse(strings.NewReader(output))
+	}
+	report(os.Stdout, flag.Arg(0), flag.Arg(1), results[0], results[1])
+}
+
+// runAt runs the benchmarks in a temporary worktree of commit and returns
+// the output of go test.
+func runAt(commit, pkg, bench string, count int) (string, error) {
+	dir, err := ioutil.TempDir("", "benchcompare")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	worktree := filepath.Join(dir, "tree")
+	if out, err := exec.Command("git", "worktree", "add", "--detach", worktree, commit).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("checking out %s: %v\n%s", commit, err, out)
+	}
+	defer exec.Command("git", "worktree", "remove", "--force", worktree).Run()
+
+	fmt.Fprintf(os.Stderr, "running benchmarks at %s\n", commit)
+	cmd := exec.Command("go", "test", "-run", "^$", "-bench", bench, "-benchmem", "-count", strconv.Itoa(count), pkg)
+	cmd.Dir = worktree
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("benchmarks at %s: %v", commit, err)
+	}
+	return string(out), nil
+}
+
+// parse collects the ns/op of every run by benchmark name. The GOMAXPROCS
+// suffix is dropped; it is only recognised when every line carries the same
+// one, since go test leaves it out with a single CPU and sub-benchmark names
+// may end in numbers of their own.
+func parse(r io.Reader) map[string][]float64 {
+	type run struct {
+		name string
+		ns   float64
+	}
+	var runs []run
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			if fields[i+1] != "ns/op" {
+				continue
+			}
+			if ns, err := strconv.ParseFloat(fields[i], 64); err == nil {
+				runs = append(runs, run{fields[0], ns})
+			}
+			break
+		}
+	}
+
+	suffix := ""
+	for i, r := range runs {
+		dash := strings.LastIndex(r.name, "-")
+		if dash == -1 {
+			suffix = ""
+			break
+		}
+		if _, err := strconv.Atoi(r.name[dash+1:]); err != nil || i > 0 && r.name[dash:] != suffix {
+			suffix = ""
+			break
+		}
+		suffix = r.name[dash:]
+	}
+	results := make(map[string][]float64)
+	for _, r := range runs {
+		name := strings.TrimSuffix(r.name, suffix)
+		results[name] = append(results[name], r.ns)
+	}
+	return results
+}
+
+func report(w io.Writer, oldName, newName string, before, after map[string][]float64) {
+	var names []string
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	width := len("benchmark")
+	for _, name := range names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	fmt.Fprintf(w, "%-*s  %18s  %18s  %8s\n", width, "benchmark", trim(oldName), trim(newName), "delta")
+	for _, name := range names {
+		o, n := before[name], after[name]
+		delta := ""
+		if len(o) > 0 && len(n) > 0 {
+			delta = fmt.Sprintf("%+.1f%%", (mean(n)/mean(o)-1)*100)
+		}
+		fmt.Fprintf(w, "%-*s  %18s  %18s  %8s\n", width, name, summary(o), summary(n), delta)
+	}
+}
+
+func trim(name string) string {
+	if len(name) > 18 {
+		return name[:18]
+	}
+	return name
+}
+
+// summary formats the mean time and the spread between runs.
+func summary(runs []float64) string {
+	if len(runs) == 0 {
+		return "-"
+	}
+	m := mean(runs)
+	spread := 0.0
+	for _, v := range runs {
+		spread = math.Max(spread, math.Abs(v-m)/m)
+	}
+	return fmt.Sprintf("%s ±%2.0f%%", duration(m), spread*100)
+}
+
+func mean(runs []float64) float64 {
+	sum := 0.0
+	for _, v := range runs {
+		sum += v
+	}
+	return sum / float64(len(runs))
+}
+
+func duration(ns float64) string {
+	switch {
+	case ns >= 1e9:
+		return fmt.Sprintf("%.2fs", ns/1e9)
+	case ns >= 1e6:
+		return fmt.Sprintf("%.2fms", ns/1e6)
+	case ns >= 1e3:
+		return fmt.Sprintf("%.2fµs", ns/1e3)
+	}
+	return fmt.Sprintf("%.0fns", ns)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/batch"
+)
+
+func runBatch(args []string) error {
+	flags := newFlags("batch", "<directory or .jsonl file>")
+	jobs := flags.Int("jobs", 0, "puzzles solved at the same time (number of CPUs when 0)")
+	timeout := flags.Duration("timeout", 0, "time limit per puzzle (30s when 0)")
+	output := flags.String("o", "", "write the JSON report to this file (standard output when empty)")
+	var search solverFlags
+	search.register(flags)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	options, err := search.options()
+	if err != nil {
+		return err
+	}
+	items, err := batch.Load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	report := batch.Run(items, batch.Options{Workers: *jobs, Timeout: *timeout, Solve: options})
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%d puzzles in %.2fs: %d solved, %d unsolved, %d timeouts, %d mismatches, %d errors\n",
+		report.Total, report.Seconds, report.Solved, report.Unsolved, report.Timeouts, report.Mismatch, report.Errors)
+	if report.Solved != report.Total {
+		return errors.New("not every puzzle was solved")
+	}
+	return nil
+}
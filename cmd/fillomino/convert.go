@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/render"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+func runConvert(args []string) error {
+	flags := newFlags("convert", "[files]")
+	from := flags.String("from", "", "input format (detected when empty)")
+	to := flags.String("to", string(formats.FormatText), "output format")
+	output := flags.String("o", "", "output file (standard output when empty)")
+	flags.Parse(args)
+
+	if err := checkFormat(*to); err != nil {
+		return err
+	}
+	inputs, err := readInputs(flags.Args(), *from)
+	if err != nil {
+		return err
+	}
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	for _, in := range inputs {
+		if err := formats.Write(formats.Format(*to), out, in.puzzle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runRender(args []string) error {
+	flags := newFlags("render", "[files]")
+	from := flags.String("from", "", "input format (detected when empty)")
+	as := flags.String("as", "svg", "output: svg, png, pdf (a booklet of all inputs) or text")
+	show := flags.String("show", "puzzle", "what to draw: puzzle or solution")
+	cell := flags.Int("cell", 40, "cell size in pixels for svg and png")
+	perPage := flags.Int("per-page", 4, "puzzles per booklet page")
+	output := flags.String("o", "", "output file (standard output when empty)")
+	flags.Parse(args)
+
+	options := render.Options{CellSize: *cell}
+	switch *show {
+	case "puzzle":
+	case "solution":
+		options.Show = render.ShowSolution
+	default:
+		return fmt.Errorf("unknown -show %q", *show)
+	}
+	inputs, err := readInputs(flags.Args(), *from)
+	if err != nil {
+		return err
+	}
+	if *as != "pdf" && len(inputs) > 1 {
+		return errors.New("only -as pdf renders more than one puzzle")
+	}
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if *as == "pdf" {
+		entries := make([]render.Entry, len(inputs))
+		for i, in := range inputs {
+			solution, err := solution(in.puzzle)
+			if err != nil {
+				return fmt.Errorf("%s: %v", in.name, err)
+			}
+			entries[i] = render.Entry{Puzzle: in.puzzle.State, Solution: solution}
+		}
+		return render.BookletPDF(out, entries, render.BookletOptions{PerPage: *perPage})
+	}
+
+	in := inputs[0]
+	var solved *solver.FieldState
+	if options.Show == render.ShowSolution {
+		solved, err = solution(in.puzzle)
+		if err != nil {
+			return fmt.Errorf("%s: %v", in.name, err)
+		}
+	}
+	return renderBoard(out, *as, in.puzzle, solved, options)
+}
+
+func renderBoard(w io.Writer, as string, puzzle *formats.Puzzle, solved *solver.FieldState, options render.Options) error {
+	if as == "text" {
+		shown := *puzzle
+		if solved != nil {
+			shown.State = solved
+		}
+		_, err := io.WriteString(w, shown.Render(formats.BoxUnicode))
+		return err
+	}
+	board, err := render.NewBoard(puzzle.State, solved, options.Show)
+	if err != nil {
+		return err
+	}
+	switch as {
+	case "svg":
+		return render.SVG(w, board, options)
+	case "png":
+		return render.PNG(w, board, options)
+	}
+	return fmt.Errorf("unknown -as %q", as)
+}
+
+// solution returns the solution the puzzle carries, or solves it.
+func solution(puzzle *formats.Puzzle) (*solver.FieldState, error) {
+	if puzzle.Solution != nil {
+		return puzzle.Solution, nil
+	}
+	ps, solved, err := newSolver(puzzle.State, solver.SolveOptions{Mode: solver.ModeRegions})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ps.Solve(); err != nil {
+		return nil, err
+	}
+	return solved, nil
+}
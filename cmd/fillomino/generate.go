@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+func runGenerate(args []string) error {
+	flags := newFlags("generate", "")
+	size := flags.Int("size", 5, "board size")
+	filled := flags.Float64("filled", 0.3, "share of the cells given as clues")
+	count := flags.Int("count", 1, "number of puzzles")
+	to := flags.String("to", string(formats.FormatText), "output format")
+	output := flags.String("o", "", "output file (standard output when empty)")
+	withSolution := flags.Bool("solution", false, "include the solution where the format allows it")
+	symmetryName := flags.String("symmetry", "none", "clue layout symmetry: none, rotate180, rotate90, horizontal, vertical or diagonal")
+	flags.Parse(args)
+
+	if err := checkFormat(*to); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return errors.New("count must be positive")
+	}
+	symmetry, err := clueSymmetry(*symmetryName)
+	if err != nil {
+		return err
+	}
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	generator := solver.NewPuzzleGenerator(*size)
+	generator.SetSymmetry(symmetry)
+	for i := 0; i < *count; i++ {
+		grid, err := generator.GeneratePuzzle(*filled)
+		if err != nil {
+			return err
+		}
+		result, err := solver.FromListToState(grid)
+		if err != nil {
+			return err
+		}
+		puzzle := &formats.Puzzle{State: result["state"].(*solver.FieldState), Metadata: map[string]string{}}
+		if *withSolution {
+			ps, solved, err := newSolver(puzzle.State, solver.SolveOptions{})
+			if err != nil {
+				return err
+			}
+			if _, err := ps.Solve(); err != nil {
+				return fmt.Errorf("generated puzzle has no solution: %v", err)
+			}
+			puzzle.Solution = solved
+		}
+		if err := formats.Write(formats.Format(*to), out, puzzle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func clueSymmetry(name string) (solver.ClueSymmetry, error) {
+	switch name {
+	case "none":
+		return solver.SymmetryNone, nil
+	case "rotate180":
+		return solver.SymmetryRotate180, nil
+	case "rotate90":
+		return solver.SymmetryRotate90, nil
+	case "horizontal":
+		return solver.SymmetryHorizontal, nil
+	case "vertical":
+		return solver.SymmetryVertical, nil
+	case "diagonal":
+		return solver.SymmetryDiagonal, nil
+	}
+	return solver.SymmetryNone, fmt.Errorf("unknown symmetry %q", name)
+}
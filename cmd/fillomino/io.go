@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// input is a puzzle together with the name it was read from.
+type input struct {
+	name   string
+	puzzle *formats.Puzzle
+}
+
+// readInputs reads one puzzle from every path, or from standard input when
+// there are none or the path is "-". An empty format detects it from the
+// content.
+func readInputs(paths []string, format string) ([]input, error) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	var inputs []input
+	for _, path := range paths {
+		var data []byte
+		var err error
+		name := path
+		if path == "-" {
+			name = "stdin"
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		f := formats.Format(format)
+		if format == "" {
+			f = formats.DetectFormat(data)
+		}
+		puzzle, err := formats.Parse(f, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		inputs = append(inputs, input{name, puzzle})
+	}
+	return inputs, nil
+}
+
+// checkFormat rejects format names the formats package does not know.
+func checkFormat(format string) error {
+	for _, f := range formats.Formats {
+		if string(f) == format {
+			return nil
+		}
+	}
+	names := make([]string, len(formats.Formats))
+	for i, f := range formats.Formats {
+		names[i] = string(f)
+	}
+	return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(names, ", "))
+}
+
+// createOutput opens path for writing, or returns standard output for ""
+// and "-".
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// solverFlags are the search options shared by the commands that solve.
+type solverFlags struct {
+	mode    string
+	workers int
+}
+
+func (f *solverFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.mode, "mode", "cells", "search mode: cells, regions or exactcover")
+	flags.IntVar(&f.workers, "workers", 1, "number of parallel search workers")
+}
+
+func (f *solverFlags) options() (solver.SolveOptions, error) {
+	options := solver.SolveOptions{Workers: f.workers}
+	switch f.mode {
+	case "cells":
+		options.Mode = solver.ModeCells
+	case "regions":
+		options.Mode = solver.ModeRegions
+	case "exactcover":
+		options.Mode = solver.ModeExactCover
+	default:
+		return options, fmt.Errorf("unknown search mode %q", f.mode)
+	}
+	return options, nil
+}
+
+// newSolver returns a solver working on a copy of the state, so the
+// puzzle keeps its givens.
+func newSolver(state *solver.FieldState, options solver.SolveOptions) (*solver.PuzzleSolver, *solver.FieldState, error) {
+	result, err := solver.FromListToState(state.ToList())
+	if err != nil {
+		return nil, nil, err
+	}
+	copied := result["state"].(*solver.FieldState)
+	ps := solver.NewPuzzleSolver(copied)
+	ps.SetOptions(options)
+	return ps, copied, nil
+}
@@ -0,0 +1,69 @@
+// Command fillomino solves, generates, checks, rates, reduces, converts and
+// renders Fillomino puzzles without the API server. Puzzles are read from
+// files or standard input in any format the formats package knows.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"solve":    {"solve puzzles and print the solved boards", runSolve},
+	"generate": {"generate new puzzles", runGenerate},
+	"check":    {"check that puzzles have exactly one solution", runCheck},
+	"batch":    {"solve a corpus of puzzles and report the results", runBatch},
+	"rate":     {"rate the difficulty of puzzles", runRate},
+	"reduce":   {"remove givens that are not needed for uniqueness", runReduce},
+	"convert":  {"convert puzzles between formats", runConvert},
+	"render":   {"draw puzzles as SVG, PNG or a PDF booklet", runRender},
+	"play":     {"play a puzzle in the terminal", runPlay},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "fillomino: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "fillomino:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: fillomino <command> [flags] [files]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun \"fillomino <command> -h\" for the flags of a command.")
+}
+
+// newFlags returns a flag set whose usage line names the command.
+func newFlags(name, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: fillomino %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+const playHelp = "arrows/hjkl move  1-9 enter  0/space clear  u undo  r redo  ? hint  q quit"
+
+func runPlay(args []string) error {
+	flags := newFlags("play", "[file]")
+	from := flags.String("from", "", "input format (detected when empty)")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		// Keys are read from standard input, so the puzzle cannot be.
+		return errors.New("play needs a puzzle file")
+	}
+	inputs, err := readInputs(flags.Args()[:1], *from)
+	if err != nil {
+		return err
+	}
+	g, err := newGame(inputs[0].puzzle)
+	if err != nil {
+		return err
+	}
+
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	fmt.Print("\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\r\n")
+
+	keys := make([]byte, 8)
+	for {
+		fmt.Print(strings.Replace(g.view(), "\n", "\r\n", -1))
+		n, err := os.Stdin.Read(keys)
+		if err != nil {
+			return err
+		}
+		if g.handle(string(keys[:n])) {
+			return nil
+		}
+	}
+}
+
+// rawTerminal switches the terminal to raw mode with stty and returns a
+// function that restores the previous settings.
+func rawTerminal() (func(), error) {
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = os.Stdin
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, errors.New("play needs an interactive terminal")
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(saved) }, nil
+}
+
+// edit is one change to a cell, kept for undo and redo.
+type edit struct {
+	cell     solver.Cell
+	old, new int
+}
+
+// game is the state of a play session.
+type game struct {
+	puzzle   *formats.Puzzle
+	givens   [][]int
+	board    *solver.FieldState
+	cursor   solver.Cell
+	undone   []edit
+	done     []edit
+	typing   bool
+	solution [][]int
+	message  string
+}
+
+func newGame(puzzle *formats.Puzzle) (*game, error) {
+	givens := puzzle.State.ToList()
+	result, err := solver.FromListToState(givens)
+	if err != nil {
+		return nil, err
+	}
+	return &game{puzzle: puzzle, givens: givens, board: result["state"].(*solver.FieldState)}, nil
+}
+
+// handle applies one key press and reports whether the player quit.
+func (g *game) handle(key string) bool {
+	g.message = ""
+	typing := g.typing
+	g.typing = false
+	switch key {
+	case "q", "\x03", "\x1b":
+		return true
+	case "\x1b[A", "k":
+		g.move(-1, 0)
+	case "\x1b[B", "j":
+		g.move(1, 0)
+	case "\x1b[D", "h":
+		g.move(0, -1)
+	case "\x1b[C", "l":
+		g.move(0, 1)
+	case "0":
+		if typing {
+			g.enter(0, true)
+		} else {
+			g.set(g.cursor, 0)
+		}
+	case " ", ".", "\x7f", "\b", "\x1b[3~":
+		g.set(g.cursor, 0)
+	case "u":
+		g.undo()
+	case "r", "\x12":
+		g.redo()
+	case "?":
+		g.hint()
+	default:
+		if len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
+			g.enter(int(key[0]-'0'), typing)
+		}
+	}
+	if g.message == "" && g.board.IsSolved() {
+		g.message = "Solved!"
+	}
+	return false
+}
+
+func (g *game) move(dx, dy int) {
+	size := len(g.givens)
+	g.cursor.X = (g.cursor.X + dx + size) % size
+	g.cursor.Y = (g.cursor.Y + dy + size) % size
+}
+
+// enter types a digit into the cursor cell. Digits typed one after the
+// other build up numbers larger than nine, as long as they fit the board.
+func (g *game) enter(digit int, typing bool) {
+	size := len(g.givens)
+	value := digit
+	if current := g.board.GetState(g.cursor); typing && current*10+digit <= size*size {
+		value = current*10 + digit
+	}
+	if value > size*size {
+		g.message = fmt.Sprintf("numbers go up to %d", size*size)
+		return
+	}
+	if g.set(g.cursor, value) {
+		g.typing = true
+	}
+}
+
+// set changes a cell that is not a given and records the change.
+func (g *game) set(cell solver.Cell, value int) bool {
+	if g.givens[cell.X][cell.Y] != 0 {
+		g.message = "that cell is a clue"
+		return false
+	}
+	old := g.board.GetState(cell)
+	if old == value {
+		return true
+	}
+	g.board.SetState(cell, value)
+	g.done = append(g.done, edit{cell, old, value})
+	g.undone = nil
+	return true
+}
+
+func (g *game) undo() {
+	if len(g.done) == 0 {
+		g.message = "nothing to undo"
+		return
+	}
+	e := g.done[len(g.done)-1]
+	g.done = g.done[:len(g.done)-1]
+	g.board.SetState(e.cell, e.old)
+	g.undone = append(g.undone, e)
+	g.cursor = e.cell
+}
+
+func (g *game) redo() {
+	if len(g.undone) == 0 {
+		g.message = "nothing to redo"
+		return
+	}
+	e := g.undone[len(g.undone)-1]
+	g.undone = g.undone[:len(g.undone)-1]
+	g.board.SetState(e.cell, e.new)
+	g.done = append(g.done, e)
+	g.cursor = e.cell
+}
+
+// hint fills in the solution at the cursor if that cell is empty or wrong,
+// otherwise at the first such cell, and moves the cursor there.
+func (g *game) hint() {
+	if g.solution == nil {
+		result, err := solver.FromListToState(g.givens)
+		if err != nil {
+			g.message = err.Error()
+			return
+		}
+		state := result["state"].(*solver.FieldState)
+		ps := solver.NewPuzzleSolver(state)
+		ps.SetOptions(solver.SolveOptions{Mode: solver.ModeRegions})
+		if _, err := ps.Solve(); err != nil {
+			g.message = "the puzzle has no solution"
+			return
+		}
+		g.solution = state.ToList()
+	}
+
+	target := g.cursor
+	if g.board.GetState(target) == g.solution[target.X][target.Y] {
+		found := false
+		for x := range g.solution {
+			for y := range g.solution[x] {
+				if !found && g.board.GetState(solver.Cell{X: x, Y: y}) != g.solution[x][y] {
+					target, found = solver.Cell{X: x, Y: y}, true
+				}
+			}
+		}
+		if !found {
+			g.message = "nothing left to hint"
+			return
+		}
+	}
+	g.cursor = target
+	g.set(target, g.solution[target.X][target.Y])
+	g.message = fmt.Sprintf("hint: %d", g.solution[target.X][target.Y])
+}
+
+// view draws the board with region borders: the cursor in reverse video,
+// givens in bold and cells of broken regions in red.
+func (g *game) view() string {
+	conflicts := make(map[solver.Cell]bool)
+	for _, cell := range g.board.Conflicts() {
+		conflicts[cell] = true
+	}
+	shown := *g.puzzle
+	shown.State = g.board
+	board := shown.RenderCells(formats.BoxUnicode, func(cell solver.Cell, text string) string {
+		codes := ""
+		if g.givens[cell.X][cell.Y] != 0 {
+			codes += "\x1b[1m"
+		}
+		if conflicts[cell] {
+			codes += "\x1b[31m"
+		}
+		if cell == g.cursor {
+			codes += "\x1b[7m"
+		}
+		if codes == "" {
+			return text
+		}
+		return codes + text + "\x1b[0m"
+	})
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	if title := g.puzzle.Metadata["title"]; title != "" {
+		b.WriteString(title + "\n")
+	}
+	b.WriteString(board)
+	b.WriteString(playHelp + "\n")
+	b.WriteString(g.message + "\n")
+	return b.String()
+}
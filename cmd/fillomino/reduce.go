@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+func runReduce(args []string) error {
+	flags := newFlags("reduce", "[files]")
+	from := flags.String("from", "", "input format (detected when empty)")
+	to := flags.String("to", string(formats.FormatText), "output format")
+	output := flags.String("o", "", "output file (standard output when empty)")
+	pin := flags.String("pin", "", "givens to keep, as row:column pairs separated by commas")
+	prefer := flags.String("prefer", "", "values whose givens are removed first, separated by commas")
+	var sf solverFlags
+	sf.register(flags)
+	flags.Parse(args)
+
+	if err := checkFormat(*to); err != nil {
+		return err
+	}
+	options := solver.ReduceOptions{}
+	var err error
+	if options.Solve, err = sf.options(); err != nil {
+		return err
+	}
+	if options.Pinned, err = parseCells(*pin); err != nil {
+		return err
+	}
+	if options.Prefer, err = parseValues(*prefer); err != nil {
+		return err
+	}
+	inputs, err := readInputs(flags.Args(), *from)
+	if err != nil {
+		return err
+	}
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, in := range inputs {
+		reduced, err := solver.Reduce(in.puzzle.State, options)
+		if err != nil {
+			return fmt.Errorf("%s: %v", in.name, err)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d givens, %d after reduction\n", in.name, givens(in.puzzle.State), givens(reduced))
+		puzzle := &formats.Puzzle{State: reduced, Metadata: in.puzzle.Metadata, Solution: in.puzzle.Solution}
+		if err := formats.Write(formats.Format(*to), out, puzzle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func givens(state *solver.FieldState) int {
+	count := 0
+	for _, row := range state.ToList() {
+		for _, value := range row {
+			if value != 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// parseCells reads "row:column" pairs separated by commas.
+func parseCells(list string) ([]solver.Cell, error) {
+	var cells []solver.Cell
+	for _, pair := range strings.Split(list, ",") {
+		if pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bad cell %q, expected row:column", pair)
+		}
+		x, errX := strconv.Atoi(parts[0])
+		y, errY := strconv.Atoi(parts[1])
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("bad cell %q, expected row:column", pair)
+		}
+		cells = append(cells, solver.Cell{X: x, Y: y})
+	}
+	return cells, nil
+}
+
+func parseValues(list string) ([]int, error) {
+	var values []int
+	for _, field := range strings.Split(list, ",") {
+		if field == "" {
+			continue
+		}
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("bad value %q", field)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
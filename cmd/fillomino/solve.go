@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+func runSolve(args []string) error {
+	flags := newFlags("solve", "[files]")
+	from := flags.String("from", "", "input format (detected when empty)")
+	to := flags.String("to", string(formats.FormatText), "output format")
+	output := flags.String("o", "", "output file (standard output when empty)")
+	var search solverFlags
+	search.register(flags)
+	flags.Parse(args)
+
+	if err := checkFormat(*to); err != nil {
+		return err
+	}
+	options, err := search.options()
+	if err != nil {
+		return err
+	}
+	inputs, err := readInputs(flags.Args(), *from)
+	if err != nil {
+		return err
+	}
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, in := range inputs {
+		ps, solved, err := newSolver(in.puzzle.State, options)
+		if err != nil {
+			return fmt.Errorf("%s: %v", in.name, err)
+		}
+		if _, err := ps.Solve(); err != nil {
+			return fmt.Errorf("%s: %v", in.name, err)
+		}
+		puzzle := *in.puzzle
+		puzzle.Solution = solved
+		if format := formats.Format(*to); format == formats.FormatText || format == formats.FormatPuzzLink {
+			// These formats carry one grid, so the solved board replaces
+			// the givens.
+			puzzle.State, puzzle.Solution = solved, nil
+		}
+		if err := formats.Write(formats.Format(*to), out, &puzzle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runCheck(args []string) error {
+	flags := newFlags("check", "[files]")
+	from := flags.String("from", "", "input format (detected when empty)")
+	var search solverFlags
+	search.register(flags)
+	flags.Parse(args)
+
+	options, err := search.options()
+	if err != nil {
+		return err
+	}
+	inputs, err := readInputs(flags.Args(), *from)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, in := range inputs {
+		status, err := check(in.puzzle, options)
+		if err != nil {
+			status = err.Error()
+		}
+		if status != "unique" {
+			failed++
+		}
+		fmt.Printf("%s: %s\n", in.name, status)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d puzzles failed the check", failed, len(inputs))
+	}
+	return nil
+}
+
+// check reports "unique" for a puzzle with exactly one solution, which
+// must match the solution the puzzle carries, if any.
+func check(puzzle *formats.Puzzle, options solver.SolveOptions) (string, error) {
+	ps, _, err := newSolver(puzzle.State, options)
+	if err != nil {
+		return "", err
+	}
+	count, err := ps.CountSolutions(2)
+	if err != nil {
+		return "", err
+	}
+	switch count {
+	case 0:
+		return "no solution", nil
+	case 2:
+		return "multiple solutions", nil
+	}
+	if puzzle.Solution == nil {
+		return "unique", nil
+	}
+	ps, solved, err := newSolver(puzzle.State, options)
+	if err != nil {
+		return "", err
+	}
+	if _, err := ps.Solve(); err != nil {
+		return "", err
+	}
+	if !sameGrid(solved.ToList(), puzzle.Solution.ToList()) {
+		return "", errors.New("unique, but the given solution is wrong")
+	}
+	return "unique", nil
+}
+
+func sameGrid(a, b [][]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for x := range a {
+		for y := range a[x] {
+			if a[x][y] != b[x][y] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func runRate(args []string) error {
+	flags := newFlags("rate", "[files]")
+	from := flags.String("from", "", "input format (detected when empty)")
+	flags.Parse(args)
+
+	inputs, err := readInputs(flags.Args(), *from)
+	if err != nil {
+		return err
+	}
+	for _, in := range inputs {
+		rating, err := solver.NewPuzzleSolver(in.puzzle.State).Rate()
+		if err != nil {
+			return fmt.Errorf("%s: %v", in.name, err)
+		}
+		difficulty := rating.Difficulty
+		switch rating.Solutions {
+		case 0:
+			difficulty = "no solution"
+		case 2:
+			difficulty = "multiple solutions"
+		}
+		fmt.Fprintf(os.Stdout, "%s: %s (givens %d, nodes %d)\n", in.name, difficulty, rating.Givens, rating.Nodes)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package batchtests
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/batch"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func emptyGrid(size int) [][]int {
+	grid := make([][]int, size)
+	for i := range grid {
+		grid[i] = make([]int, size)
+	}
+	return grid
+}
+
+func TestReadJSONL(t *testing.T) {
+	wide, _ := json.Marshal(emptyGrid(41))
+	text, _ := json.Marshal(strings.Repeat(strings.Repeat(".", 41)+"\n", 41))
+	corpus := `{"name": "grid", "grid": [[1, 0], [0, 0]]}
+
+{"puzzle": "https://puzz.link/p?fillomino/4/4/h2g3j1i4h", "expected_hash": "abc"}
+{"name": "empty"}
+not json
+{"name": "wide", "grid": ` + string(wide) + `}
+{"name": "ragged", "grid": [[1, 0], [0]]}
+{"name": "wide text", "puzzle": ` + string(text) + `}
+{"name": "small text", "puzzle": "1.\n..\n"}
+`
+	items, err := batch.ReadJSONL(strings.NewReader(corpus))
+	assert.Equal(t, err, nil)
+	samples := []struct {
+		name     string
+		expected string
+		failed   bool
+	}{
+		{"grid", "", false},
+		{"line 3", "abc", false},
+		{"empty", "", true},
+		{"line 5", "", true},
+		{"wide", "", true},
+		{"ragged", "", true},
+		{"wide text", "", true},
+		{"small text", "", false},
+	}
+	assert.Equal(t, len(items), len(samples))
+	for i, v := range samples {
+		assert.Equal(t, items[i].Name, v.name)
+		assert.Equal(t, items[i].ExpectedHash, v.expected)
+		assert.Equal(t, items[i].Err != nil, v.failed)
+	}
+}
+
+func TestRun(t *testing.T) {
+	big, _ := json.Marshal(emptyGrid(20))
+	corpus := `{"name": "solved", "grid": [[1, 0], [0, 0]]}
+{"name": "unsolved", "grid": [[1, 0], [0, 1]]}
+{"name": "mismatch", "grid": [[1, 0], [0, 0]], "expected_hash": "0000000000000000"}
+{"name": "timeout", "grid": ` + string(big) + `}
+not json
+`
+	items, err := batch.ReadJSONL(strings.NewReader(corpus))
+	assert.Equal(t, err, nil)
+	report := batch.Run(items, batch.Options{Workers: 2, Timeout: 50 * time.Millisecond})
+
+	statuses := []string{batch.StatusSolved, batch.StatusUnsolved, batch.StatusMismatch, batch.StatusTimeout, batch.StatusError}
+	for i, status := range statuses {
+		assert.Equal(t, report.Results[i].Status, status)
+	}
+	assert.Equal(t, report.Total, 5)
+	assert.Equal(t, report.Solved, 1)
+	assert.Equal(t, report.Unsolved, 1)
+	assert.Equal(t, report.Mismatch, 1)
+	assert.Equal(t, report.Timeouts, 1)
+	assert.Equal(t, report.Errors, 1)
+	assert.Equal(t, report.Results[0].Hash, report.Results[2].Hash)
+	assert.Equal(t, report.Results[0].Hash, batch.Hash([][]int{{1, 3}, {3, 3}}))
+}
+
+func TestRunCancel(t *testing.T) {
+	items := make([]batch.Item, 4)
+	for i := range items {
+		big, _ := json.Marshal(emptyGrid(20))
+		parsed, err := batch.ReadJSONL(strings.NewReader(`{"name": "big", "grid": ` + string(big) + `}`))
+		assert.Equal(t, err, nil)
+		items[i] = parsed[0]
+	}
+	cancel := make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() { close(cancel) })
+	done := 0
+	report := batch.Run(items, batch.Options{
+		Workers:  1,
+		Timeout:  time.Minute,
+		Cancel:   cancel,
+		Progress: func(d, total int) { done = d },
+	})
+
+	assert.Equal(t, report.Results[0].Status, batch.StatusTimeout)
+	assert.Equal(t, report.Timeouts+report.Cancel, len(items))
+	assert.Equal(t, done, report.Timeouts)
+	assert.Equal(t, report.Seconds < 10, true)
+}
@@ -0,0 +1,105 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestSolveBatch(t *testing.T) {
+
+	_, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+	corpus := `{"name": "solved", "grid": [[1, 0], [0, 0]]}
+{"name": "unsolved", "grid": [[1, 0], [0, 1]]}
+`
+
+	samples := []struct {
+		query        string
+		corpus       string
+		tokenGiven   string
+		statusCode   int
+		errorMessage string
+	}{
+		{
+			query:      "?timeout=1&workers=1000",
+			tokenGiven: tokenString,
+			statusCode: 200,
+		},
+		{
+			// Two puzzles at a minute each may take longer than a request.
+			query:      "?timeout=60&workers=1",
+			tokenGiven: tokenString,
+			statusCode: 202,
+		},
+		{
+			query:        "?timeout=600",
+			tokenGiven:   tokenString,
+			statusCode:   400,
+			errorMessage: "timeout must be between 0 and 60 seconds",
+		},
+		{
+			query:        "?timeout=1",
+			corpus:       strings.Repeat(" \n", 5<<20),
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "http: request body too large",
+		},
+		{
+			query:        "",
+			tokenGiven:   "This is an incorrect token",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+	}
+
+	for _, v := range samples {
+
+		if v.corpus == "" {
+			v.corpus = corpus
+		}
+		req, err := http.NewRequest("POST", "/batch"+v.query, bytes.NewBufferString(v.corpus))
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.SolveBatch)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["total"], float64(2))
+			assert.Equal(t, responseMap["solved"], float64(1))
+		}
+		if v.statusCode == 202 {
+			job := waitForJob(t, responseMap["id"].(string))
+			assert.Equal(t, job.Status, jobs.StatusSucceeded)
+			assert.Equal(t, job.Report.Total, 2)
+			assert.Equal(t, job.Report.Unsolved, 1)
+		}
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+}
@@ -9,12 +9,13 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/joho/godotenv"
 	"github.com/alcoccoque/puzzle-solver-go/api/controllers"
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
 	"github.com/alcoccoque/puzzle-solver-go/api/models"
 )
 
 var server = controllers.Server{}
 var userInstance = models.User{}
-var postInstance = models.Post{}
+var matrixInstance = models.Matrix{}
 
 func TestMain(m *testing.M) {
 	err := godotenv.Load(os.ExpandEnv("../../.env"))
@@ -22,6 +23,7 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Error getting env %v\n", err)
 	}
 	Database()
+	server.Jobs = jobs.NewQueue(jobs.Options{})
 
 	os.Exit(m.Run())
 
@@ -59,11 +61,15 @@ func Database() {
 
 func refreshUserTable() error {
 
-	err := server.DB.DropTableIfExists(&models.Post{}, &models.User{}).Error
+	err := server.DB.DropTableIfExists(&models.Matrix{}, &models.User{}).Error
 	if err != nil {
 		return err
 	}
-	err = server.DB.AutoMigrate(&models.User{}, &models.Post{}).Error
+	err = server.DB.AutoMigrate(&models.User{}).Error
+	if err != nil {
+		return err
+	}
+	err = models.Migrate(server.DB)
 	if err != nil {
 		return err
 	}
@@ -119,13 +125,17 @@ func seedUsers() ([]models.User, error) {
 	return users, nil
 }
 
-func refreshUserAndPostTable() error {
+func refreshUserAndMatrixTable() error {
 
-	err := server.DB.DropTableIfExists(&models.Post{}, &models.User{}).Error
+	err := server.DB.DropTableIfExists(&models.Matrix{}, &models.User{}).Error
 	if err != nil {
 		return err
 	}
-	err = server.DB.AutoMigrate(&models.User{}, &models.Post{}).Error
+	err = server.DB.AutoMigrate(&models.User{}).Error
+	if err != nil {
+		return err
+	}
+	err = models.Migrate(server.DB)
 	if err != nil {
 		return err
 	}
@@ -133,11 +143,11 @@ func refreshUserAndPostTable() error {
 	return nil
 }
 
-func seedOneUserAndOnePost() (models.Post, error) {
+func seedOneUserAndOneMatrix() (models.Matrix, error) {
 
-	err := refreshUserAndPostTable()
+	err := refreshUserAndMatrixTable()
 	if err != nil {
-		return models.Post{}, err
+		return models.Matrix{}, err
 	}
 	user := models.User{
 		Nickname: "Sam Phil",
@@ -146,61 +156,22 @@ func seedOneUserAndOnePost() (models.Post, error) {
 	}
 	err = server.DB.Model(&models.User{}).Create(&user).Error
 	if err != nil {
-		return models.Post{}, err
-	}
-	post := models.Post{
-		Title:    "This is the title sam",
-		Content:  "This is the content sam",
-		AuthorID: user.ID,
-	}
-	err = server.DB.Model(&models.Post{}).Create(&post).Error
+		return models.Matrix{}, err
+	}
+	matrix := models.Matrix{UserID: user.ID}
+	matrix.Prepare()
+	err = matrix.SetClues([][]int{
+		{0, 0, 2, 0},
+		{3, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 4, 0, 0},
+	})
 	if err != nil {
-		return models.Post{}, err
+		return models.Matrix{}, err
 	}
-	return post, nil
-}
-
-func seedUsersAndPosts() ([]models.User, []models.Post, error) {
-
-	var err error
-
+	err = server.DB.Model(&models.Matrix{}).Create(&matrix).Error
 	if err != nil {
-		return []models.User{}, []models.Post{}, err
-	}
-	var users = []models.User{
-		models.User{
-			Nickname: "Test",
-			Email:    "test@gmail.com",
-			Password: "password",
-		},
-		models.User{
-			Nickname: "Magu Frank",
-			Email:    "magu@gmail.com",
-			Password: "password",
-		},
-	}
-	var posts = []models.Post{
-		models.Post{
-			Title:   "Title 1",
-			Content: "Hello world 1",
-		},
-		models.Post{
-			Title:   "Title 2",
-			Content: "Hello world 2",
-		},
-	}
-
-	for i, _ := range users {
-		err = server.DB.Model(&models.User{}).Create(&users[i]).Error
-		if err != nil {
-			log.Fatalf("cannot seed users table: %v", err)
-		}
-		posts[i].AuthorID = users[i].ID
-
-		err = server.DB.Model(&models.Post{}).Create(&posts[i]).Error
-		if err != nil {
-			log.Fatalf("cannot seed posts table: %v", err)
-		}
+		return models.Matrix{}, err
 	}
-	return users, posts, nil
+	return matrix, nil
 }
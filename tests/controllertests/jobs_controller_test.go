@@ -0,0 +1,197 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
+	"github.com/alcoccoque/puzzle-solver-go/api/models"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestGetJobAndEvents(t *testing.T) {
+
+	matrix, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	other := models.User{
+		Nickname: "Kenny Morris",
+		Email:    "kenny@gmail.com",
+		Password: "password",
+	}
+	err = server.DB.Model(&models.User{}).Create(&other).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	job, err := server.Jobs.Submit("test", matrix.UserID, func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+		return jobs.Output{MatrixID: matrix.ID}, nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	waitForJob(t, job.ID)
+
+	ownerToken, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	otherToken, err := server.SignIn("kenny@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	samples := []struct {
+		id           string
+		tokenGiven   string
+		statusCode   int
+		errorMessage string
+	}{
+		{
+			id:         job.ID,
+			tokenGiven: fmt.Sprintf("Bearer %v", ownerToken),
+			statusCode: 200,
+		},
+		{
+			id:           job.ID,
+			tokenGiven:   fmt.Sprintf("Bearer %v", otherToken),
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+		{
+			id:           job.ID,
+			tokenGiven:   "This is an incorrect token",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+		{
+			id:           "abcdef",
+			tokenGiven:   fmt.Sprintf("Bearer %v", ownerToken),
+			statusCode:   404,
+			errorMessage: "Job Not Found",
+		},
+	}
+
+	for _, v := range samples {
+
+		req, err := http.NewRequest("GET", "/jobs", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.GetJob)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["matrix_id"], float64(matrix.ID))
+		}
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+
+		// The event stream answers the same; its token may come in the URL.
+		req, err = http.NewRequest("GET", "/jobs/events?token="+strings.TrimPrefix(v.tokenGiven, "Bearer "), nil)
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		rr = httptest.NewRecorder()
+		handler = http.HandlerFunc(server.JobEvents)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, strings.HasPrefix(rr.Body.String(), "event: done\n"), true)
+		}
+	}
+}
+
+func TestSolveJob(t *testing.T) {
+
+	matrix, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+	wide, _ := json.Marshal(map[string][][]int{"grid": make([][]int, 41)})
+
+	samples := []struct {
+		inputJSON    string
+		tokenGiven   string
+		statusCode   int
+		errorMessage string
+	}{
+		{
+			inputJSON:  `{"grid": [[1, 0, 2], [0, 0, 3], [0, 0, 2]]}`,
+			tokenGiven: tokenString,
+			statusCode: 202,
+		},
+		{
+			inputJSON:  fmt.Sprintf(`{"matrix_id": %d}`, matrix.ID),
+			tokenGiven: tokenString,
+			statusCode: 202,
+		},
+		{
+			inputJSON:    string(wide),
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "board side must be between 2 and 40",
+		},
+		{
+			inputJSON:    `{"grid": [[1, 0, 2], [0, 0], [0, 0, 2]]}`,
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "row 1 has 2 cells, expected 3",
+		},
+		{
+			inputJSON:    `{"grid": [[1, 0], [0, 1]]}`,
+			tokenGiven:   "This is an incorrect token",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+	}
+
+	for _, v := range samples {
+
+		req, err := http.NewRequest("POST", "/solve", strings.NewReader(v.inputJSON))
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.SolveJob)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 202 {
+			job := waitForJob(t, responseMap["id"].(string))
+			assert.Equal(t, job.Status, jobs.StatusSucceeded)
+		}
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+}
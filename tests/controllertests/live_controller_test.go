@@ -0,0 +1,41 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/live"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestLiveSolveBusy(t *testing.T) {
+
+	server.LiveSolves = live.NewSlots(1)
+	defer func() { server.LiveSolves = nil }()
+	assert.Equal(t, server.LiveSolves.Take(), true)
+
+	req, err := http.NewRequest("GET", "/solve/live?puzzle=https://puzz.link/p?fillomino/4/4/h2g3j1i4h", nil)
+	if err != nil {
+		t.Errorf("this is the error: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.LiveSolve)
+	handler.ServeHTTP(rr, req)
+
+	responseMap := make(map[string]interface{})
+	err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, rr.Code, http.StatusServiceUnavailable)
+	assert.Equal(t, responseMap["error"], "too many live solves, try again later")
+
+	// Once a slot is free the request gets as far as the upgrade.
+	server.LiveSolves.Release()
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusUpgradeRequired)
+	assert.Equal(t, server.LiveSolves.Take(), true)
+}
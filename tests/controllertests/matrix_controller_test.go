@@ -0,0 +1,618 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
+	"github.com/alcoccoque/puzzle-solver-go/api/models"
+	"github.com/alcoccoque/puzzle-solver-go/api/pool"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestSolveMatrix(t *testing.T) {
+
+	matrix, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		inputJSON    string
+		tokenGiven   string
+		statusCode   int
+		solution     string
+		errorMessage string
+	}{
+		{
+			inputJSON:  `{"rows": [[1, 0, 2], [0, 0, 3], [0, 0, 2]]}`,
+			tokenGiven: tokenString,
+			statusCode: 201,
+			solution:   "[[1,2,2],[3,3,3],[1,2,2]]",
+		},
+		{
+			// The seeded matrix is solved again but not stored twice.
+			inputJSON:  `{"rows": [[0, 0, 2, 0], [3, 0, 0, 0], [0, 1, 0, 0], [0, 4, 0, 0]]}`,
+			tokenGiven: tokenString,
+			statusCode: 200,
+		},
+		{
+			inputJSON:    `{"rows": [[3, 0, 0], [0, 3, 0], [0, 0, 3]]}`,
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "puzzle is unsolvable",
+		},
+		{
+			inputJSON:    `{"rows": [[1, 0, 2], [0, 0], [0, 0, 2]]}`,
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "row 1 has 2 cells, expected 3",
+		},
+		{
+			inputJSON:    `{"rows": [[1]]}`,
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "board side must be between 2 and 40",
+		},
+		{
+			inputJSON:    `{"rows": [[1, 0], [0, 1]]}`,
+			tokenGiven:   "This is an incorrect token",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+	}
+
+	for _, v := range samples {
+
+		req, err := http.NewRequest("POST", "/matrices/solve", bytes.NewBufferString(v.inputJSON))
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.SolveMatrix)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.solution != "" {
+			solution, _ := json.Marshal(responseMap["solution"])
+			assert.Equal(t, string(solution), v.solution)
+			stored := responseMap["matrix"].(map[string]interface{})
+			assert.Equal(t, stored["user_id"], float64(matrix.UserID))
+		}
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+}
+
+func TestImportPuzzLink(t *testing.T) {
+
+	_, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		inputJSON    string
+		statusCode   int
+		errorMessage string
+	}{
+		{
+			inputJSON:  `{"url": "https://puzz.link/p?fillomino/5/5/-10g3zh"}`,
+			statusCode: 201,
+		},
+		{
+			// A mirror image of the seeded matrix.
+			inputJSON:  `{"url": "https://puzz.link/p?fillomino/4/4/g2k3h1i4g"}`,
+			statusCode: 200,
+		},
+		{
+			inputJSON:    `{"url": "https://puzz.link/p?fillomino/99999/99999/"}`,
+			statusCode:   422,
+			errorMessage: "boards larger than 40x40 are not supported, got 99999x99999",
+		},
+		{
+			inputJSON:    `{"url": "https://puzz.link/p?nurikabe/2/2/j"}`,
+			statusCode:   422,
+			errorMessage: "not a fillomino link",
+		},
+	}
+
+	for _, v := range samples {
+
+		req, err := http.NewRequest("POST", "/matrices/import", bytes.NewBufferString(v.inputJSON))
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req.Header.Set("Authorization", tokenString)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.ImportPuzzLink)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+}
+
+func TestFindMatrixByFingerprint(t *testing.T) {
+
+	matrix, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// A second user stores the same puzzle.
+	other := models.User{Nickname: "Kenny Morris", Email: "kenny@gmail.com", Password: "password"}
+	err = server.DB.Model(&models.User{}).Create(&other).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	copied := models.Matrix{UserID: other.ID}
+	copied.Prepare()
+	copied.SetClues(matrix.Clues)
+	err = server.DB.Model(&models.Matrix{}).Create(&copied).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	assert.Equal(t, copied.Fingerprint, matrix.Fingerprint)
+
+	samples := []struct {
+		email       string
+		fingerprint string
+		statusCode  int
+		id          uint64
+	}{
+		{email: "sam@gmail.com", fingerprint: string(matrix.Fingerprint), statusCode: 200, id: matrix.ID},
+		{email: "kenny@gmail.com", fingerprint: string(matrix.Fingerprint), statusCode: 200, id: copied.ID},
+		{email: "kenny@gmail.com", fingerprint: strings.Repeat("0", 64), statusCode: 404},
+		{fingerprint: string(matrix.Fingerprint), statusCode: 401},
+	}
+
+	for _, v := range samples {
+
+		req, err := http.NewRequest("GET", "/matrices/fingerprint", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		if v.email != "" {
+			token, err := server.SignIn(v.email, "password")
+			if err != nil {
+				log.Fatalf("cannot login: %v\n", err)
+			}
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+		}
+		req = mux.SetURLVars(req, map[string]string{"fingerprint": v.fingerprint})
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.FindMatrixByFingerprint)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["id"], float64(v.id))
+		}
+	}
+}
+
+func TestExportPuzzLink(t *testing.T) {
+
+	matrix, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		id         string
+		statusCode int
+		url        string
+	}{
+		{
+			id:         strconv.Itoa(int(matrix.ID)),
+			statusCode: 200,
+			url:        "https://puzz.link/p?fillomino/4/4/h2g3j1i4h",
+		},
+		{
+			id:         "unknown",
+			statusCode: 400,
+		},
+		{
+			id:         "12345",
+			statusCode: 400,
+		},
+	}
+
+	for _, v := range samples {
+
+		req, err := http.NewRequest("GET", "/matrices", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.ExportPuzzLink)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["url"], v.url)
+		}
+	}
+}
+
+func TestRenderMatrix(t *testing.T) {
+
+	matrix, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	solved := models.Matrix{UserID: matrix.UserID}
+	solved.Prepare()
+	err = solved.SetClues([][]int{{1, 0, 2}, {0, 0, 3}, {0, 0, 2}})
+	if err == nil {
+		err = solved.SetSolution([][]int{{1, 2, 2}, {3, 3, 3}, {1, 2, 2}})
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = server.DB.Model(&models.Matrix{}).Create(&solved).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		id         string
+		query      string
+		statusCode int
+	}{
+		{
+			id:         strconv.Itoa(int(matrix.ID)),
+			statusCode: 200,
+		},
+		{
+			id:         strconv.Itoa(int(solved.ID)),
+			query:      "?show=solution",
+			statusCode: 200,
+		},
+		{
+			// The seeded matrix has more than one solution, so none is stored.
+			id:         strconv.Itoa(int(matrix.ID)),
+			query:      "?show=solution",
+			statusCode: 404,
+		},
+		{
+			id:         strconv.Itoa(int(matrix.ID)),
+			query:      "?show=answer",
+			statusCode: 400,
+		},
+		{
+			id:         "12345",
+			statusCode: 400,
+		},
+	}
+
+	for _, v := range samples {
+
+		req, err := http.NewRequest("GET", "/matrices/"+v.id+".svg"+v.query, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.RenderMatrix)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, rr.Header().Get("Content-Type"), "image/svg+xml")
+			assert.Equal(t, strings.HasPrefix(rr.Body.String(), "<svg") || strings.HasPrefix(rr.Body.String(), "<?xml"), true)
+		}
+	}
+}
+
+func TestBooklet(t *testing.T) {
+
+	matrix, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	solved := models.Matrix{UserID: matrix.UserID}
+	solved.Prepare()
+	err = solved.SetClues([][]int{{1, 0, 2}, {0, 0, 3}, {0, 0, 2}})
+	if err == nil {
+		err = solved.SetSolution([][]int{{1, 2, 2}, {3, 3, 3}, {1, 2, 2}})
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = server.DB.Model(&models.Matrix{}).Create(&solved).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		query        string
+		tokenGiven   string
+		statusCode   int
+		contentType  string
+		errorMessage string
+	}{
+		{
+			query:       fmt.Sprintf("?ids=%d", solved.ID),
+			tokenGiven:  tokenString,
+			statusCode:  200,
+			contentType: "application/pdf",
+		},
+		{
+			query:       fmt.Sprintf("?ids=%d&format=png", solved.ID),
+			tokenGiven:  tokenString,
+			statusCode:  200,
+			contentType: "image/png",
+		},
+		{
+			query:        fmt.Sprintf("?ids=%d,%d", solved.ID, matrix.ID),
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: fmt.Sprintf("matrix %d has no stored solution", matrix.ID),
+		},
+		{
+			query:        "?generate=100&size=6&filled_percentage=0.3",
+			tokenGiven:   tokenString,
+			statusCode:   400,
+			errorMessage: "generate must be between 1 and 20",
+		},
+		{
+			query:        "?generate=2&size=99999&filled_percentage=0.3",
+			tokenGiven:   tokenString,
+			statusCode:   400,
+			errorMessage: "size must be between 2 and 40",
+		},
+		{
+			query:      "?generate=2&size=4&filled_percentage=0.4",
+			tokenGiven: tokenString,
+			statusCode: 202,
+		},
+		{
+			query:        fmt.Sprintf("?ids=%d", solved.ID),
+			tokenGiven:   "This is an incorrect token",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+	}
+
+	for _, v := range samples {
+
+		req, err := http.NewRequest("GET", "/booklet"+v.query, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.Booklet)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.contentType != "" {
+			assert.Equal(t, rr.Header().Get("Content-Type"), v.contentType)
+		}
+		if v.errorMessage != "" {
+			responseMap := make(map[string]interface{})
+			err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+		if v.statusCode == 202 {
+			job := jobs.Job{}
+			err = json.Unmarshal([]byte(rr.Body.String()), &job)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			job = waitForJob(t, job.ID)
+			assert.Equal(t, job.Status, jobs.StatusSucceeded)
+			assert.Equal(t, len(job.MatrixIDs), 2)
+		}
+	}
+}
+
+// waitForJob polls the job queue until the job has finished.
+func waitForJob(t *testing.T, id string) jobs.Job {
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		job, err := server.Jobs.Get(id)
+		if err != nil {
+			t.Fatalf("cannot get the job: %v", err)
+		}
+		if job.Status.Finished() || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGenerateMatrix(t *testing.T) {
+
+	_, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+	wide, _ := json.Marshal(map[string][][]int{"template": make([][]int, 41)})
+
+	samples := []struct {
+		query        string
+		inputJSON    string
+		tokenGiven   string
+		statusCode   int
+		errorMessage string
+	}{
+		{
+			query:      "?size=4&filled_percentage=0.4",
+			tokenGiven: tokenString,
+			statusCode: 202,
+		},
+		{
+			query:        "?size=99999&filled_percentage=0.4",
+			tokenGiven:   tokenString,
+			statusCode:   400,
+			errorMessage: "size must be between 2 and 40",
+		},
+		{
+			query:        "?size=-3&filled_percentage=0.4",
+			tokenGiven:   tokenString,
+			statusCode:   400,
+			errorMessage: "size must be between 2 and 40",
+		},
+		{
+			inputJSON:    string(wide),
+			tokenGiven:   tokenString,
+			statusCode:   400,
+			errorMessage: "board side must be between 2 and 40",
+		},
+		{
+			inputJSON:    `{"template": [[-1, 0, 0], [0, 0], [0, 0, -1]]}`,
+			tokenGiven:   tokenString,
+			statusCode:   400,
+			errorMessage: "row 1 has 2 cells, expected 3",
+		},
+		{
+			query:        "?size=4&filled_percentage=0.4",
+			tokenGiven:   "This is an incorrect token",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+	}
+
+	for _, v := range samples {
+
+		req, err := http.NewRequest("POST", "/generate"+v.query, bytes.NewBufferString(v.inputJSON))
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+		}
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.GenerateMatrix)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 202 {
+			job := waitForJob(t, responseMap["id"].(string))
+			assert.Equal(t, job.Status, jobs.StatusSucceeded)
+			assert.NotEqual(t, job.MatrixID, uint64(0))
+		}
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+}
+
+// TestGenerateMatrixFromPool takes a stocked puzzle, whose solution and
+// difficulty are copied onto the matrix rather than found again.
+func TestGenerateMatrixFromPool(t *testing.T) {
+
+	_, err := seedOneUserAndOneMatrix()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	bucket := pool.Bucket{Size: 5, Difficulty: solver.DifficultyExpert}
+	store := pool.NewMemoryStore()
+	server.Pool = pool.New(pool.Options{Buckets: []pool.Bucket{bucket}, Store: store})
+	defer func() { server.Pool = nil }()
+	solution := [][]int{
+		{5, 5, 5, 5, 5},
+		{1, 4, 4, 4, 4},
+		{3, 3, 3, 1, 2},
+		{4, 2, 2, 3, 2},
+		{4, 4, 4, 3, 3},
+	}
+	clues := [][]int{
+		{0, 5, 5, 5, 5},
+		{1, 4, 4, 0, 4},
+		{3, 3, 0, 1, 2},
+		{4, 2, 2, 3, 2},
+		{4, 4, 4, 3, 0},
+	}
+	// The clues are easy, so a matrix rated again would not say expert.
+	err = store.AddPuzzle(bucket, pool.Puzzle{Clues: clues, Solution: solution, Difficulty: bucket.Difficulty})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "/generate?size=5&difficulty=expert", bytes.NewBufferString(""))
+	if err != nil {
+		t.Errorf("this is the error: %v", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.GenerateMatrix)
+	handler.ServeHTTP(rr, req)
+
+	matrix := models.Matrix{}
+	err = json.Unmarshal([]byte(rr.Body.String()), &matrix)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, rr.Code, 201)
+	assert.Equal(t, [][]int(matrix.Clues), clues)
+	assert.Equal(t, [][]int(matrix.Solution), solution)
+	assert.Equal(t, matrix.Difficulty, solver.DifficultyExpert)
+}
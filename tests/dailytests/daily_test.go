@@ -0,0 +1,101 @@
+package dailytests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/daily"
+	"github.com/alcoccoque/puzzle-solver-go/api/pool"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func scheduler(store daily.Store, stock *pool.Pool, now *time.Time) *daily.Scheduler {
+	s := daily.NewScheduler(daily.Options{Size: 4, Difficulty: solver.DifficultyEasy, Attempts: 200, Store: store, Pool: stock})
+	s.SetClock(func() time.Time { return *now })
+	return s
+}
+
+func TestGet(t *testing.T) {
+	now := time.Date(2024, 3, 9, 23, 0, 0, 0, time.UTC)
+	s := scheduler(daily.NewMemoryStore(), nil, &now)
+	assert.Equal(t, s.Today(), "2024-03-09")
+
+	// Get does not pick; until the worker did, today is not ready.
+	_, err := s.Get("2024-03-09")
+	assert.Equal(t, err, daily.ErrNotReady)
+	picked, err := s.Pick("2024-03-09")
+	assert.Equal(t, err, nil)
+
+	puzzle, err := s.Get("2024-03-09")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, puzzle, picked)
+	assert.Equal(t, puzzle.Date, "2024-03-09")
+	assert.Equal(t, puzzle.Size, 4)
+	assert.Equal(t, puzzle.Difficulty, solver.DifficultyEasy)
+	again, err := s.Get("2024-03-09")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, again, puzzle)
+
+	samples := []struct {
+		date string
+		err  error
+	}{
+		{"2024-03-10", daily.ErrNotYet},
+		{"2024-03-08", daily.ErrNotFound},
+	}
+	for _, v := range samples {
+		_, err := s.Get(v.date)
+		assert.Equal(t, err, v.err)
+	}
+	_, err = s.Get("9 March")
+	assert.NotEqual(t, err, nil)
+
+	// A day later the puzzle stays the puzzle of its date.
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, s.Today(), "2024-03-10")
+	past, err := s.Get("2024-03-09")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, past, puzzle)
+	_, err = s.Get("2024-03-10")
+	assert.Equal(t, err, daily.ErrNotReady)
+	_, err = s.Pick("2024-03-10")
+	assert.Equal(t, err, nil)
+	next, err := s.Get("2024-03-10")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, next.Date, "2024-03-10")
+}
+
+func TestPickFromPool(t *testing.T) {
+	bucket := pool.Bucket{Size: 4, Difficulty: solver.DifficultyEasy}
+	stock := pool.New(pool.Options{Buckets: []pool.Bucket{bucket}, Level: 1, Attempts: 200})
+	assert.Equal(t, stock.Refill(nil), nil)
+
+	now := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	_, err := scheduler(nil, stock, &now).Pick("2024-03-09")
+	assert.Equal(t, err, nil)
+	_, err = stock.Take(bucket)
+	assert.Equal(t, err, pool.ErrEmpty)
+}
+
+func TestWorker(t *testing.T) {
+	store := daily.NewMemoryStore()
+	now := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	s := scheduler(store, nil, &now)
+	s.Start()
+	defer s.Stop()
+
+	for _, date := range []string{"2024-03-09", "2024-03-10"} {
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			_, err := store.FindDaily(date)
+			if err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("the worker did not pick the puzzle of %s", date)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
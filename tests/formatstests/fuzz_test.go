@@ -0,0 +1,66 @@
+package formatstests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+)
+
+// fuzzFormat checks that the parser of a format never panics and that
+// whatever it accepts is written back in a form it reads to the same grid.
+func fuzzFormat(f *testing.F, format formats.Format, seeds ...string) {
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		puzzle, err := formats.Parse(format, data)
+		if err != nil {
+			return
+		}
+		var out bytes.Buffer
+		if err := formats.Write(format, &out, puzzle); err != nil {
+			t.Fatalf("writing %q: %v", data, err)
+		}
+		again, err := formats.Parse(format, out.Bytes())
+		if err != nil {
+			t.Fatalf("reading back %q: %v", out.String(), err)
+		}
+		if !reflect.DeepEqual(again.State.ToList(), puzzle.State.ToList()) {
+			t.Fatalf("%q read back as %v, expected %v", out.String(), again.State.ToList(), puzzle.State.ToList())
+		}
+	})
+}
+
+func FuzzParseText(f *testing.F) {
+	fuzzFormat(f, formats.FormatText,
+		"title: Example\n3.|.\n--.\n.2.\n",
+		"10 . .\n. . .\n. . 1\n",
+		"# comment\n12\n21\n",
+	)
+}
+
+func FuzzDecodePuzzLink(f *testing.F) {
+	fuzzFormat(f, formats.FormatPuzzLink,
+		"https://puzz.link/p?fillomino/4/4/h2g3j1i4h",
+		"https://puzz.link/p?fillomino/v:/3/3/-1ag+123j",
+		"fillomino/2/2/zz",
+	)
+}
+
+func FuzzParseJanko(f *testing.F) {
+	fuzzFormat(f, formats.FormatJanko, jankoPuzzle, "[problem]\n1 -\n- -\n[end]\n")
+}
+
+func FuzzParsePzprFile(f *testing.F) {
+	fuzzFormat(f, formats.FormatPzpr, pzprPuzzle, "pzprv3\nfillomino\n2\n2\n1 .\n. +3\n")
+}
+
+func FuzzDecodeKudamono(f *testing.F) {
+	fuzzFormat(f, formats.FormatKudamono,
+		"https://pedros.works/paper-puzzle-player?W=4x4&L=3(3)2(4)1(1)6(2)&G=fillomino",
+		"https://pedros.works/paper-puzzle-player?W=3&G=fillomino&L=1(16)8(5)",
+		"W=2x2&G=fillomino",
+	)
+}
@@ -0,0 +1,166 @@
+package formatstests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+const jankoPuzzle = `[setup]
+author = Otto Janko
+puzzle = fillomino
+size   = 3
+[problem]
+3 - 1
+- - -
+2 - 3
+[solution]
+3 3 1
+3 2 3
+2 2 3
+[moves]
+ignored
+[end]
+`
+
+const pzprPuzzle = `pzprv3
+fillomino
+3
+3
+3 . 1 
+. +2 . 
+2 . 3 
+0 1 
+0 0 
+0 1 
+1 0 0 
+0 1 0 
+`
+
+func TestParseJanko(t *testing.T) {
+
+	puzzle, err := formats.ParseJanko(bytes.NewReader([]byte(jankoPuzzle)))
+	if err != nil {
+		t.Errorf("this is the error parsing the puzzle: %v\n", err)
+		return
+	}
+	assert.Equal(t, puzzle.State.ToList(), [][]int{{3, 0, 1}, {0, 0, 0}, {2, 0, 3}})
+	assert.Equal(t, puzzle.Solution.ToList(), [][]int{{3, 3, 1}, {3, 2, 3}, {2, 2, 3}})
+	assert.Equal(t, puzzle.Metadata, map[string]string{"author": "Otto Janko", "size": "3"})
+
+	var out bytes.Buffer
+	err = formats.WriteJanko(&out, puzzle)
+	if err != nil {
+		t.Errorf("this is the error writing the puzzle: %v\n", err)
+		return
+	}
+	again, err := formats.ParseJanko(&out)
+	if err != nil {
+		t.Errorf("this is the error parsing the written puzzle: %v\n", err)
+		return
+	}
+	assert.Equal(t, again.State.ToList(), puzzle.State.ToList())
+	assert.Equal(t, again.Solution.ToList(), puzzle.Solution.ToList())
+}
+
+func TestParsePzprFile(t *testing.T) {
+
+	puzzle, err := formats.ParsePzprFile(bytes.NewReader([]byte(pzprPuzzle)))
+	if err != nil {
+		t.Errorf("this is the error parsing the puzzle: %v\n", err)
+		return
+	}
+	assert.Equal(t, puzzle.State.ToList(), [][]int{{3, 0, 1}, {0, 0, 0}, {2, 0, 3}})
+	assert.Equal(t, puzzle.Solution.ToList(), [][]int{{3, 0, 1}, {0, 2, 0}, {2, 0, 3}})
+	assert.Equal(t, len(puzzle.Walls), 4)
+
+	var out bytes.Buffer
+	err = formats.WritePzprFile(&out, puzzle)
+	if err != nil {
+		t.Errorf("this is the error writing the puzzle: %v\n", err)
+		return
+	}
+	assert.Equal(t, out.String(), pzprPuzzle)
+}
+
+func TestParseJankoErrors(t *testing.T) {
+
+	large := strings.Repeat(strings.TrimSpace(strings.Repeat("- ", 41))+"\n", 41)
+	samples := []struct {
+		file         string
+		errorMessage string
+	}{
+		{
+			file:         "[setup]\npuzzle = nurikabe\n[problem]\n- -\n- -\n",
+			errorMessage: "not a fillomino puzzle: nurikabe",
+		},
+		{
+			file:         "[problem]\n" + large,
+			errorMessage: "problem: boards larger than 40x40 are not supported, got 41x41",
+		},
+		{
+			file:         "[problem]\n- -\n- -\n[solution]\n" + large,
+			errorMessage: "solution: boards larger than 40x40 are not supported, got 41x41",
+		},
+	}
+
+	for _, v := range samples {
+		_, err := formats.ParseJanko(bytes.NewReader([]byte(v.file)))
+		if err == nil {
+			t.Errorf("expected an error for %q", v.file)
+			continue
+		}
+		assert.Equal(t, err.Error(), v.errorMessage)
+	}
+}
+
+func TestParsePzprFileErrors(t *testing.T) {
+
+	samples := []struct {
+		file         string
+		errorMessage string
+	}{
+		{
+			file:         "pzprv3\nfillomino\n-1\n-1\n.\n",
+			errorMessage: "invalid board size -1x-1",
+		},
+		{
+			file:         "pzprv3\nfillomino\n2\n3\n. . .\n. . .\n",
+			errorMessage: "only square boards are supported, got 3x2",
+		},
+		{
+			file:         "pzprv3\nfillomino\n99999\n99999\n.\n",
+			errorMessage: "boards larger than 40x40 are not supported, got 99999x99999",
+		},
+	}
+
+	for _, v := range samples {
+		_, err := formats.ParsePzprFile(bytes.NewReader([]byte(v.file)))
+		if err == nil {
+			t.Errorf("expected an error for %q", v.file)
+			continue
+		}
+		assert.Equal(t, err.Error(), v.errorMessage)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+
+	samples := []struct {
+		input  string
+		format formats.Format
+	}{
+		{input: jankoPuzzle, format: formats.FormatJanko},
+		{input: pzprPuzzle, format: formats.FormatPzpr},
+		{input: "https://puzz.link/p?fillomino/4/4/h2g3j1i4h\n", format: formats.FormatPuzzLink},
+		{input: "https://pedros.works/paper-puzzle-player?W=4x4&L=3(3)&G=fillomino\n", format: formats.FormatKudamono},
+		{input: "title: x\n3.1\n...\n2.3\n", format: formats.FormatText},
+	}
+
+	for _, v := range samples {
+		assert.Equal(t, formats.DetectFormat([]byte(v.input)), v.format)
+	}
+}
@@ -0,0 +1,108 @@
+package formatstests
+
+import (
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestDecodeKudamono(t *testing.T) {
+
+	samples := []struct {
+		link         string
+		grid         [][]int
+		errorMessage string
+	}{
+		{
+			link: "https://pedros.works/paper-puzzle-player?W=4x4&L=3(3)2(4)1(1)6(2)&G=fillomino",
+			grid: [][]int{
+				{0, 0, 2, 0},
+				{3, 0, 0, 0},
+				{0, 1, 0, 0},
+				{0, 4, 0, 0},
+			},
+		},
+		{
+			link: "https://pedros.works/paper-puzzle-player?W=3&G=fillomino&L=1(16)8(5)",
+			grid: [][]int{
+				{0, 0, 5},
+				{0, 0, 0},
+				{16, 0, 0},
+			},
+		},
+		{
+			link: "https://pedros.works/paper-puzzle-player?W=2x2&G=fillomino",
+			grid: [][]int{
+				{0, 0},
+				{0, 0},
+			},
+		},
+		{
+			link:         "https://pedros.works/paper-puzzle-player?W=4x3&L=1(1)&G=fillomino",
+			errorMessage: "only square boards are supported, got 4x3",
+		},
+		{
+			link:         "https://pedros.works/paper-puzzle-player?W=99999&G=fillomino",
+			errorMessage: "boards larger than 40x40 are not supported, got 99999x99999",
+		},
+		{
+			link:         "https://pedros.works/paper-puzzle-player?W=2x2&L=1(?)&G=fillomino",
+			errorMessage: `clue "?" is not supported`,
+		},
+		{
+			link:         "https://pedros.works/paper-puzzle-player?W=2x2&L=2(1)3(2)&G=fillomino",
+			errorMessage: `clue "2" lies outside the board`,
+		},
+		{
+			link:         "https://pedros.works/paper-puzzle-player?W=2x2&L=0(1)&G=fillomino",
+			errorMessage: `invalid clue offset "0"`,
+		},
+		{
+			link:         "https://pedros.works/paper-puzzle-player?W=2x2&L=1(1&G=fillomino",
+			errorMessage: "clue list ends inside a clue",
+		},
+		{
+			link:         "https://pedros.works/paper-puzzle-player?W=2x2&L=1(1)2&G=fillomino",
+			errorMessage: `clue list ends with "2"`,
+		},
+		{
+			link:         "https://pedros.works/paper-puzzle-player?G=fillomino",
+			errorMessage: "link is missing the board size",
+		},
+		{
+			link:         "https://pedros.works/paper-puzzle-player?W=2x2&G=nurikabe",
+			errorMessage: "not a fillomino link",
+		},
+	}
+
+	for _, v := range samples {
+
+		state, err := formats.DecodeKudamono(v.link)
+		if err != nil {
+			assert.Equal(t, err.Error(), v.errorMessage)
+			continue
+		}
+		assert.Equal(t, v.errorMessage, "")
+		assert.Equal(t, state.ToList(), v.grid)
+	}
+}
+
+func TestEncodeKudamono(t *testing.T) {
+
+	links := []string{
+		"https://pedros.works/paper-puzzle-player?W=4x4&L=3(3)2(4)1(1)6(2)&G=fillomino",
+		"https://pedros.works/paper-puzzle-player?W=3x3&L=1(16)8(5)&G=fillomino",
+		"https://pedros.works/paper-puzzle-player?W=5x5&L=&G=fillomino",
+	}
+
+	for _, link := range links {
+
+		state, err := formats.DecodeKudamono(link)
+		if err != nil {
+			t.Errorf("this is the error decoding the link: %v\n", err)
+			continue
+		}
+		assert.Equal(t, formats.EncodeKudamono(state), link)
+	}
+}
@@ -0,0 +1,85 @@
+package formatstests
+
+import (
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestDecodePuzzLink(t *testing.T) {
+
+	samples := []struct {
+		link         string
+		grid         [][]int
+		errorMessage string
+	}{
+		{
+			link: "https://puzz.link/p?fillomino/4/4/h2g3j1i4h",
+			grid: [][]int{
+				{0, 0, 2, 0},
+				{3, 0, 0, 0},
+				{0, 1, 0, 0},
+				{0, 4, 0, 0},
+			},
+		},
+		{
+			link: "http://pzv.jp/p.html?fillomino/3/3/-10n",
+			grid: [][]int{
+				{16, 0, 0},
+				{0, 0, 0},
+				{0, 0, 0},
+			},
+		},
+		{
+			link:         "https://puzz.link/p?fillomino/4/3/l",
+			errorMessage: "only square boards are supported, got 4x3",
+		},
+		{
+			link:         "https://puzz.link/p?fillomino/99999/99999/",
+			errorMessage: "boards larger than 40x40 are not supported, got 99999x99999",
+		},
+		{
+			link:         "https://puzz.link/p?fillomino/-3/-3/j",
+			errorMessage: "invalid board size -3x-3",
+		},
+		{
+			link:         "https://puzz.link/p?fillomino/2/2/.i",
+			errorMessage: "question mark clue at cell 0 is not supported",
+		},
+		{
+			link:         "https://puzz.link/p?nurikabe/2/2/j",
+			errorMessage: "not a fillomino link",
+		},
+	}
+
+	for _, v := range samples {
+
+		state, err := formats.DecodePuzzLink(v.link)
+		if err != nil {
+			assert.Equal(t, err.Error(), v.errorMessage)
+			continue
+		}
+		assert.Equal(t, v.errorMessage, "")
+		assert.Equal(t, state.ToList(), v.grid)
+	}
+}
+
+func TestEncodePuzzLink(t *testing.T) {
+
+	links := []string{
+		"https://puzz.link/p?fillomino/4/4/h2g3j1i4h",
+		"https://puzz.link/p?fillomino/5/5/-10g3zh",
+		"https://puzz.link/p?fillomino/5/5/zk",
+	}
+
+	for _, link := range links {
+
+		state, err := formats.DecodePuzzLink(link)
+		if err != nil {
+			t.Errorf("this is the error decoding the link: %v\n", err)
+			continue
+		}
+		assert.Equal(t, formats.EncodePuzzLink(state), link)
+	}
+}
@@ -0,0 +1,115 @@
+package formatstests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/formats"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+const samplePuzzle = `# sample puzzle
+title: Demo
+author: Test
+33|1
+-..
+3..  # trailing comment
+.22
+`
+
+func TestParseText(t *testing.T) {
+
+	puzzle, err := formats.ParseText(strings.NewReader(samplePuzzle))
+	if err != nil {
+		t.Errorf("this is the error parsing the puzzle: %v\n", err)
+		return
+	}
+	assert.Equal(t, puzzle.State.ToList(), [][]int{{3, 3, 1}, {3, 0, 0}, {0, 2, 2}})
+	assert.Equal(t, puzzle.Metadata, map[string]string{"title": "Demo", "author": "Test"})
+	assert.Equal(t, puzzle.Walls, []formats.Wall{
+		{A: solver.Cell{X: 0, Y: 1}, B: solver.Cell{X: 0, Y: 2}},
+		{A: solver.Cell{X: 0, Y: 0}, B: solver.Cell{X: 1, Y: 0}},
+	})
+
+	spaced, err := formats.ParseText(strings.NewReader("12 . 3\n. | 4 .\n. . 1\n"))
+	if err != nil {
+		t.Errorf("this is the error parsing the puzzle: %v\n", err)
+		return
+	}
+	assert.Equal(t, spaced.State.ToList(), [][]int{{12, 0, 3}, {0, 4, 0}, {0, 0, 1}})
+}
+
+func TestParseTextErrors(t *testing.T) {
+
+	samples := []struct {
+		input        string
+		errorMessage string
+	}{
+		{
+			input:        "",
+			errorMessage: "no grid found",
+		},
+		{
+			input:        "12\n3.\n..\n",
+			errorMessage: "row 1 has 2 cells, expected 3",
+		},
+		{
+			input:        "1x\n..\n",
+			errorMessage: "line 1: invalid cell \"x\"",
+		},
+		{
+			input:        "..\ntitle: late\n..\n",
+			errorMessage: "line 2: header after the grid",
+		},
+		{
+			input:        strings.Repeat(strings.Repeat(".", 41)+"\n", 41),
+			errorMessage: "boards larger than 40x40 are not supported, got 41x41",
+		},
+	}
+
+	for _, v := range samples {
+
+		_, err := formats.ParseText(strings.NewReader(v.input))
+		if err == nil {
+			t.Errorf("expected an error for %q\n", v.input)
+			continue
+		}
+		assert.Equal(t, err.Error(), v.errorMessage)
+	}
+}
+
+func TestWriteTextRoundTrip(t *testing.T) {
+
+	puzzle, err := formats.ParseText(strings.NewReader(samplePuzzle))
+	if err != nil {
+		t.Errorf("this is the error parsing the puzzle: %v\n", err)
+		return
+	}
+	var out bytes.Buffer
+	err = formats.WriteText(&out, puzzle)
+	if err != nil {
+		t.Errorf("this is the error writing the puzzle: %v\n", err)
+		return
+	}
+	assert.Equal(t, out.String(), "author: Test\ntitle: Demo\n33|1\n-..\n3..\n.22\n")
+}
+
+func TestRender(t *testing.T) {
+
+	puzzle, err := formats.ParseText(strings.NewReader("331\n3..\n.22\n"))
+	if err != nil {
+		t.Errorf("this is the error parsing the puzzle: %v\n", err)
+		return
+	}
+	expected := "" +
+		"+---+---+---+\n" +
+		"| 3   3 | 1 |\n" +
+		"+       +   +\n" +
+		"| 3   .   . |\n" +
+		"+           +\n" +
+		"| .   2   2 |\n" +
+		"+---+---+---+\n"
+	assert.Equal(t, puzzle.Render(formats.BoxASCII), expected)
+}
@@ -0,0 +1,221 @@
+package jobstests
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/jobs"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// waitFor polls a job until it reaches a status matching done.
+func waitFor(t *testing.T, q *jobs.Queue, id string, done func(jobs.Status) bool) jobs.Job {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(id)
+		if err != nil {
+			t.Fatalf("job %s: %v", id, err)
+		}
+		if done(job.Status) {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not get there", id)
+	return jobs.Job{}
+}
+
+func wait(t *testing.T, q *jobs.Queue, id string) jobs.Job {
+	return waitFor(t, q, id, jobs.Status.Finished)
+}
+
+func running(status jobs.Status) bool {
+	return status == jobs.StatusRunning
+}
+
+// blocking returns a task that runs until released or cancelled.
+func blocking(release <-chan struct{}) jobs.Task {
+	return func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+		select {
+		case <-release:
+			return jobs.Output{MatrixID: 1}, nil
+		case <-cancel:
+			return jobs.Output{}, errors.New("stopped")
+		}
+	}
+}
+
+// memoryStore records every saved state.
+type memoryStore struct {
+	mu    sync.Mutex
+	saved map[string][]jobs.Job
+}
+
+func (s *memoryStore) SaveJob(job jobs.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved[job.ID] = append(s.saved[job.ID], job)
+	return nil
+}
+
+func (s *memoryStore) FindJob(id string) (jobs.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := s.saved[id]
+	if len(saved) == 0 {
+		return jobs.Job{}, jobs.ErrNotFound
+	}
+	return saved[len(saved)-1], nil
+}
+
+func (s *memoryStore) statuses(id string) []jobs.Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var statuses []jobs.Status
+	for _, job := range s.saved[id] {
+		statuses = append(statuses, job.Status)
+	}
+	return statuses
+}
+
+// slowStore holds up the saves of queued jobs, as a busy database might.
+type slowStore struct {
+	*memoryStore
+}
+
+func (s slowStore) SaveJob(job jobs.Job) error {
+	if job.Status == jobs.StatusQueued {
+		time.Sleep(50 * time.Millisecond)
+	}
+	return s.memoryStore.SaveJob(job)
+}
+
+func TestTransitions(t *testing.T) {
+	samples := []struct {
+		from, to jobs.Status
+		allowed  bool
+	}{
+		{jobs.StatusQueued, jobs.StatusRunning, true},
+		{jobs.StatusQueued, jobs.StatusCancelled, true},
+		{jobs.StatusQueued, jobs.StatusSucceeded, false},
+		{jobs.StatusRunning, jobs.StatusSucceeded, true},
+		{jobs.StatusRunning, jobs.StatusFailed, true},
+		{jobs.StatusRunning, jobs.StatusCancelled, true},
+		{jobs.StatusRunning, jobs.StatusQueued, false},
+		{jobs.StatusSucceeded, jobs.StatusFailed, false},
+		{jobs.StatusCancelled, jobs.StatusRunning, false},
+	}
+	for _, v := range samples {
+		assert.Equal(t, v.from.CanMove(v.to), v.allowed)
+	}
+	for _, status := range []jobs.Status{jobs.StatusSucceeded, jobs.StatusFailed, jobs.StatusCancelled} {
+		assert.Equal(t, status.Finished(), true)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	q := jobs.NewQueue(jobs.Options{Workers: 2, Budget: 50 * time.Millisecond})
+	samples := []struct {
+		task     jobs.Task
+		status   jobs.Status
+		matrixID uint64
+		err      string
+	}{
+		{func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+			report(jobs.Progress{Fraction: 0.5})
+			return jobs.Output{MatrixID: 7}, nil
+		}, jobs.StatusSucceeded, 7, ""},
+		{func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+			return jobs.Output{}, errors.New("no puzzle")
+		}, jobs.StatusFailed, 0, "no puzzle"},
+		{func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+			<-cancel
+			return jobs.Output{MatrixID: 3}, errors.New("stopped")
+		}, jobs.StatusFailed, 3, jobs.ErrBudgetExceeded.Error()},
+		{blocking(nil), jobs.StatusFailed, 0, jobs.ErrBudgetExceeded.Error()},
+		{func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+			panic("solver bug")
+		}, jobs.StatusFailed, 0, "solver bug"},
+	}
+	for _, v := range samples {
+		submitted, err := q.Submit("test", 1, v.task)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, submitted.Kind, "test")
+		job := wait(t, q, submitted.ID)
+		assert.Equal(t, job.Status, v.status)
+		assert.Equal(t, job.MatrixID, v.matrixID)
+		assert.Equal(t, job.Error, v.err)
+		if v.status == jobs.StatusSucceeded {
+			assert.Equal(t, job.Fraction, 1.0)
+		}
+	}
+
+	_, err := q.Get("missing")
+	assert.Equal(t, err, jobs.ErrNotFound)
+}
+
+func TestQueueFull(t *testing.T) {
+	q := jobs.NewQueue(jobs.Options{Workers: 1, Capacity: 1})
+	release := make(chan struct{})
+	first, err := q.Submit("test", 1, blocking(release))
+	assert.Equal(t, err, nil)
+	// Wait for the worker to take the first job so the second one fills
+	// the queue.
+	waitFor(t, q, first.ID, running)
+	second, err := q.Submit("test", 1, blocking(release))
+	assert.Equal(t, err, nil)
+	_, err = q.Submit("test", 1, blocking(release))
+	assert.Equal(t, err, jobs.ErrQueueFull)
+
+	close(release)
+	assert.Equal(t, wait(t, q, first.ID).Status, jobs.StatusSucceeded)
+	assert.Equal(t, wait(t, q, second.ID).Status, jobs.StatusSucceeded)
+}
+
+func TestCancel(t *testing.T) {
+	store := &memoryStore{saved: map[string][]jobs.Job{}}
+	q := jobs.NewQueue(jobs.Options{Workers: 1, Store: store})
+	release := make(chan struct{})
+	defer close(release)
+
+	first, _ := q.Submit("test", 1, blocking(release))
+	waitFor(t, q, first.ID, running)
+	second, _ := q.Submit("test", 1, blocking(release))
+
+	assert.Equal(t, q.Cancel(second.ID), nil)
+	assert.Equal(t, q.Cancel(first.ID), nil)
+	assert.Equal(t, wait(t, q, first.ID).Status, jobs.StatusCancelled)
+	assert.Equal(t, wait(t, q, second.ID).Status, jobs.StatusCancelled)
+	assert.Equal(t, q.Cancel(first.ID), jobs.ErrFinished)
+	assert.Equal(t, q.Cancel("missing"), jobs.ErrNotFound)
+
+	assert.Equal(t, store.statuses(first.ID), []jobs.Status{jobs.StatusQueued, jobs.StatusRunning, jobs.StatusCancelled})
+	assert.Equal(t, store.statuses(second.ID), []jobs.Status{jobs.StatusQueued, jobs.StatusCancelled})
+}
+
+// TestSaveOrder checks that a slow save of the queued state cannot land
+// after the worker saved a later one. The queued save may be dropped if
+// the worker gets there first, but never written out of order.
+func TestSaveOrder(t *testing.T) {
+	store := &memoryStore{saved: map[string][]jobs.Job{}}
+	q := jobs.NewQueue(jobs.Options{Workers: 1, Store: slowStore{store}})
+	submitted, err := q.Submit("test", 1, func(cancel <-chan struct{}, report func(jobs.Progress)) (jobs.Output, error) {
+		return jobs.Output{MatrixID: 1}, nil
+	})
+	assert.Equal(t, err, nil)
+	wait(t, q, submitted.ID)
+
+	// The worker saves the final state right after the job finishes.
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if found, err := store.FindJob(submitted.ID); err == nil && found.Status.Finished() {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	statuses := store.statuses(submitted.ID)
+	order := []jobs.Status{jobs.StatusQueued, jobs.StatusRunning, jobs.StatusSucceeded}
+	assert.Equal(t, statuses, order[len(order)-len(statuses):])
+}
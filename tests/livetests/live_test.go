@@ -0,0 +1,154 @@
+package livetests
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/live"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// fakeConn hands the messages sent to the client over a channel and reads
+// the client's messages from another one, closed to hang up.
+type fakeConn struct {
+	sent     chan []byte
+	received chan []byte
+}
+
+func newConn() *fakeConn {
+	return &fakeConn{sent: make(chan []byte, 100000), received: make(chan []byte)}
+}
+
+func (c *fakeConn) WriteText(data []byte) error {
+	c.sent <- data
+	return nil
+}
+
+func (c *fakeConn) ReadMessage() ([]byte, error) {
+	message, ok := <-c.received
+	if !ok {
+		return nil, errors.New("closed")
+	}
+	return message, nil
+}
+
+func toState(t *testing.T, grid [][]int) *solver.FieldState {
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result["state"].(*solver.FieldState)
+}
+
+func empty(size int) [][]int {
+	grid := make([][]int, size)
+	for x := range grid {
+		grid[x] = make([]int, size)
+	}
+	return grid
+}
+
+// start runs a live solve in the background and returns the end message
+// once it is done.
+func start(t *testing.T, conn *fakeConn, grid [][]int, options live.Options) <-chan live.End {
+	state := toState(t, grid)
+	done := make(chan live.End, 1)
+	go func() {
+		err := live.Run(conn, state, options)
+		assert.Equal(t, err, nil)
+		close(conn.sent)
+		var end live.End
+		for data := range conn.sent {
+			json.Unmarshal(data, &end)
+		}
+		done <- end
+	}()
+	return done
+}
+
+func TestRun(t *testing.T) {
+	conn := newConn()
+	defer close(conn.received)
+	grid := [][]int{{5, 0, 0, 2}, {0, 5, 0, 2}, {5, 4, 0, 0}, {1, 2, 0, 0}}
+	err := live.Run(conn, toState(t, grid), live.Options{Rate: live.MaxRate})
+	assert.Equal(t, err, nil)
+	close(conn.sent)
+
+	moves := 0
+	var end live.End
+	for data := range conn.sent {
+		var move live.Move
+		json.Unmarshal(data, &move)
+		switch move.Type {
+		case live.TypeAssign:
+			grid[move.X][move.Y] = move.Value
+			moves++
+		case live.TypeUndo:
+			grid[move.X][move.Y] = 0
+			moves++
+		default:
+			json.Unmarshal(data, &end)
+		}
+	}
+	assert.Equal(t, end.Type, live.TypeSolved)
+	assert.Equal(t, end.Moves, moves)
+	assert.Equal(t, end.Grid, [][]int{{5, 5, 1, 2}, {5, 5, 4, 2}, {5, 4, 4, 4}, {1, 2, 2, 1}})
+	assert.Equal(t, grid, end.Grid)
+}
+
+func TestRunRate(t *testing.T) {
+	conn := newConn()
+	defer close(conn.received)
+	began := time.Now()
+	end := <-start(t, conn, empty(3), live.Options{Rate: 100})
+	assert.Equal(t, end.Type, live.TypeSolved)
+	// Every move waits for its own tick.
+	assert.Equal(t, time.Since(began) >= time.Duration(end.Moves-1)*10*time.Millisecond, true)
+}
+
+func TestRunStop(t *testing.T) {
+	samples := []struct {
+		stop func(conn *fakeConn)
+	}{
+		{func(conn *fakeConn) { conn.received <- []byte("stop") }},
+		{func(conn *fakeConn) { conn.received <- []byte(`{"type": "stop"}`) }},
+		{func(conn *fakeConn) { close(conn.received) }},
+	}
+	for _, v := range samples {
+		conn := newConn()
+		done := start(t, conn, empty(8), live.Options{Rate: 10})
+		v.stop(conn)
+		end := <-done
+		assert.Equal(t, end.Type, live.TypeStopped)
+		assert.Equal(t, end.Grid == nil, true)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	conn := newConn()
+	defer close(conn.received)
+	end := <-start(t, conn, empty(8), live.Options{Rate: 10, Timeout: 50 * time.Millisecond})
+	assert.Equal(t, end.Type, live.TypeTimeout)
+}
+
+func TestRunUnsolvable(t *testing.T) {
+	conn := newConn()
+	defer close(conn.received)
+	end := <-start(t, conn, [][]int{{3, 0, 0}, {0, 3, 0}, {0, 0, 3}}, live.Options{Rate: live.MaxRate})
+	assert.Equal(t, end.Type, live.TypeUnsolvable)
+	assert.Equal(t, end.Error, "puzzle is unsolvable")
+}
+
+func TestSlots(t *testing.T) {
+	slots := live.NewSlots(2)
+	assert.Equal(t, slots.Take(), true)
+	assert.Equal(t, slots.Take(), true)
+	assert.Equal(t, slots.Take(), false)
+	slots.Release()
+	assert.Equal(t, slots.Take(), true)
+
+	assert.Equal(t, cap(live.NewSlots(0)), live.DefaultSlots)
+}
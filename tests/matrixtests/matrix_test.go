@@ -0,0 +1,302 @@
+package matrixtests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/models"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" // sqlite database driver
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var (
+	clues    = [][]int{{1, 0, 2}, {0, 0, 3}, {0, 0, 2}}
+	solution = [][]int{{1, 2, 2}, {3, 3, 3}, {1, 2, 2}}
+)
+
+// database opens an empty in-memory database with a user to own matrices.
+func database(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a database of its own.
+	db.DB().SetMaxOpenConns(1)
+	err = db.AutoMigrate(&models.User{}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec("INSERT INTO users (id, nickname, email, password) VALUES (1, 'Test', 'test@gmail.com', 'password')").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSetClues(t *testing.T) {
+	m := models.Matrix{UserID: 1}
+	m.Prepare()
+	assert.Equal(t, m.SetClues(clues), nil)
+	assert.Equal(t, m.Width, 3)
+	assert.Equal(t, m.Height, 3)
+	assert.Equal(t, m.Variant, models.VariantFillomino)
+	assert.Equal(t, len(m.Fingerprint), 64)
+	assert.Equal(t, m.Validate(), nil)
+
+	grid, err := m.Grid()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, grid, clues)
+	grid[0][0] = 9
+	assert.Equal(t, m.Clues[0][0], 1)
+
+	_, err = m.SolutionGrid()
+	assert.Equal(t, err, models.ErrNoSolution)
+
+	samples := []struct {
+		grid [][]int
+		err  string
+	}{
+		{nil, "Required Clues"},
+		{[][]int{{1, 2}, {3}}, "Row 1 Has 1 Cells, Expected 2"},
+	}
+	for _, v := range samples {
+		assert.Equal(t, m.SetClues(v.grid).Error(), v.err)
+	}
+
+	// Rectangular boards are stored, though only square ones have a
+	// fingerprint.
+	assert.Equal(t, m.SetClues([][]int{{1, 0, 0}, {0, 0, 1}}), nil)
+	assert.Equal(t, m.Width, 3)
+	assert.Equal(t, m.Height, 2)
+	assert.Equal(t, string(m.Fingerprint), "")
+}
+
+func TestSetSolution(t *testing.T) {
+	m := models.Matrix{}
+	m.SetClues(clues)
+	samples := []struct {
+		grid [][]int
+		err  string
+	}{
+		{[][]int{{1, 2}, {3, 3}}, "Grid Has 2 Rows, Expected 3"},
+		{[][]int{{1, 2, 2}, {3, 0, 3}, {1, 2, 2}}, "Solution Is Empty At 1,1"},
+		{[][]int{{2, 2, 1}, {3, 3, 3}, {1, 2, 2}}, "Solution Does Not Match The Clue At 0,0"},
+	}
+	for _, v := range samples {
+		assert.Equal(t, m.SetSolution(v.grid).Error(), v.err)
+	}
+	assert.Equal(t, m.SetSolution(solution), nil)
+	grid, err := m.SolutionGrid()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, grid, solution)
+
+	// New clues drop the solution of the old ones.
+	m.SetClues(clues)
+	assert.Equal(t, m.Solution == nil, true)
+}
+
+func TestRate(t *testing.T) {
+	m := models.Matrix{}
+	m.SetClues(clues)
+	assert.Equal(t, m.Rate(nil), nil)
+	assert.Equal(t, [][]int(m.Solution), solution)
+	assert.NotEqual(t, m.Difficulty, "")
+
+	// A puzzle with several solutions has neither.
+	m.SetClues([][]int{{0, 0}, {0, 0}})
+	assert.Equal(t, m.Rate(nil), nil)
+	assert.Equal(t, m.Solution == nil, true)
+	assert.Equal(t, m.Difficulty, "")
+}
+
+func TestSaveMatrix(t *testing.T) {
+	db := database(t)
+	assert.Equal(t, models.Migrate(db), nil)
+
+	m := models.Matrix{UserID: 1, Source: "test"}
+	m.Prepare()
+	m.SetClues(clues)
+	m.SetSolution(solution)
+	saved, err := m.SaveMatrix(db)
+	assert.Equal(t, err, nil)
+
+	found, err := (&models.Matrix{}).FindMatrixByID(db, saved.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, found.Width, 3)
+	assert.Equal(t, found.Height, 3)
+	assert.Equal(t, [][]int(found.Clues), clues)
+	assert.Equal(t, [][]int(found.Solution), solution)
+	assert.Equal(t, found.Variant, models.VariantFillomino)
+	assert.Equal(t, found.Fingerprint, m.Fingerprint)
+
+	// Migrating again leaves the table alone.
+	assert.Equal(t, models.Migrate(db), nil)
+	found, err = (&models.Matrix{}).FindMatrixByID(db, saved.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, [][]int(found.Clues), clues)
+}
+
+// TestMigrateLegacy builds the matrices table as it was with one flat
+// coordinates column and migrates it.
+func TestMigrateLegacy(t *testing.T) {
+	db := database(t)
+	steps := []string{
+		`CREATE TABLE matrices (id integer primary key autoincrement, coordinates jsonb NOT NULL, user_id int REFERENCES users(id), source varchar(255), fingerprint varchar(64), created_at datetime, updated_at datetime)`,
+		`CREATE INDEX idx_matrices_fingerprint ON matrices(fingerprint)`,
+		`INSERT INTO matrices (id, coordinates, user_id, source, created_at, updated_at) VALUES (4, '{1,0,2,0,0,3,0,0,2}', 1, 'legacy', '2020-01-02 03:04:05', '2020-01-02 03:04:05')`,
+		`INSERT INTO matrices (id, coordinates, user_id, created_at, updated_at) VALUES (7, '[1,0,0,1]', 1, '2020-01-02 03:04:05', '2020-01-02 03:04:05')`,
+	}
+	for _, step := range steps {
+		if err := db.Exec(step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, models.Migrate(db), nil)
+	assert.Equal(t, db.Dialect().HasColumn("matrices", "coordinates"), false)
+	assert.Equal(t, db.HasTable("matrices_legacy"), false)
+
+	samples := []struct {
+		id     uint64
+		clues  [][]int
+		source string
+	}{
+		{4, clues, "legacy"},
+		{7, [][]int{{1, 0}, {0, 1}}, ""},
+	}
+	for _, v := range samples {
+		found, err := (&models.Matrix{}).FindMatrixByID(db, v.id)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, [][]int(found.Clues), v.clues)
+		assert.Equal(t, found.Width, len(v.clues))
+		assert.Equal(t, found.Source, v.source)
+		assert.Equal(t, found.Variant, models.VariantFillomino)
+		assert.NotEqual(t, string(found.Fingerprint), "")
+		assert.Equal(t, found.CreatedAt.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)), true)
+	}
+
+	// New matrices carry on after the copied ids.
+	m := models.Matrix{UserID: 1}
+	m.Prepare()
+	m.SetClues([][]int{{2, 2}, {0, 1}})
+	saved, err := m.SaveMatrix(db)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.ID, uint64(8))
+}
+
+func TestMigrateBadLegacy(t *testing.T) {
+	db := database(t)
+	steps := []string{
+		`CREATE TABLE matrices (id integer primary key autoincrement, coordinates jsonb NOT NULL, user_id int)`,
+		`INSERT INTO matrices (id, coordinates, user_id) VALUES (1, '{1,2,3}', 1)`,
+	}
+	for _, step := range steps {
+		if err := db.Exec(step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := models.Migrate(db)
+	assert.Equal(t, err.Error(), "migration 0001_matrix_grids: matrix 1: 3 coordinates are not a square grid")
+	// The failed migration is rolled back whole.
+	assert.Equal(t, db.Dialect().HasColumn("matrices", "coordinates"), true)
+	assert.Equal(t, db.HasTable("matrices_legacy"), false)
+}
+
+func TestSaveMatrixDuplicate(t *testing.T) {
+	db := database(t)
+	assert.Equal(t, models.Migrate(db), nil)
+
+	m := models.Matrix{UserID: 1}
+	m.Prepare()
+	m.SetClues(clues)
+	saved, err := m.SaveMatrix(db)
+	assert.Equal(t, err, nil)
+
+	// A mirror image is the same puzzle.
+	mirror := models.Matrix{UserID: 1}
+	mirror.Prepare()
+	mirror.SetClues([][]int{{2, 0, 1}, {3, 0, 0}, {2, 0, 0}})
+	existing, err := mirror.SaveMatrix(db)
+	assert.Equal(t, err, models.ErrDuplicateMatrix)
+	assert.Equal(t, existing.ID, saved.ID)
+
+	// The index holds even when SaveMatrix is bypassed.
+	again := models.Matrix{UserID: 1}
+	again.Prepare()
+	again.SetClues(clues)
+	assert.NotEqual(t, db.Create(&again).Error, nil)
+
+	// Another user stores the puzzle as their own.
+	err = db.Exec("INSERT INTO users (id, nickname, email, password) VALUES (2, 'Other', 'other@gmail.com', 'password')").Error
+	assert.Equal(t, err, nil)
+	other := models.Matrix{UserID: 2}
+	other.Prepare()
+	other.SetClues(clues)
+	stored, err := other.SaveMatrix(db)
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, stored.ID, saved.ID)
+	assert.Equal(t, stored.UserID, uint32(2))
+
+	// Each user gets their own matrix back.
+	mirror.UserID = 2
+	mirror.Prepare()
+	existing, err = mirror.SaveMatrix(db)
+	assert.Equal(t, err, models.ErrDuplicateMatrix)
+	assert.Equal(t, existing.ID, stored.ID)
+
+	// Boards without a fingerprint are not held to it.
+	for i := 0; i < 2; i++ {
+		rectangle := models.Matrix{UserID: 1}
+		rectangle.Prepare()
+		rectangle.SetClues([][]int{{1, 0, 0}, {0, 0, 1}})
+		_, err = rectangle.SaveMatrix(db)
+		assert.Equal(t, err, nil)
+	}
+}
+
+// TestMigrateUniqueFingerprints builds the matrices table as it was with a
+// plain index on the fingerprint, holding a puzzle twice for one user and
+// once for another, and migrates it.
+func TestMigrateUniqueFingerprints(t *testing.T) {
+	db := database(t)
+	m := models.Matrix{}
+	m.SetClues(clues)
+	steps := []string{
+		`CREATE TABLE matrices (id integer primary key autoincrement, width integer NOT NULL DEFAULT 0, height integer NOT NULL DEFAULT 0, clues text, solution text, variant varchar(32) NOT NULL DEFAULT 'fillomino', difficulty varchar(16), fingerprint varchar(64), source varchar(255), metadata text, user_id int REFERENCES users(id), created_at datetime, updated_at datetime)`,
+		`CREATE INDEX idx_matrices_fingerprint ON matrices(fingerprint)`,
+		`INSERT INTO matrices (id, width, height, clues, fingerprint, user_id) VALUES (1, 3, 3, '[[1,0,2],[0,0,3],[0,0,2]]', '` + string(m.Fingerprint) + `', 1)`,
+		`INSERT INTO matrices (id, width, height, clues, fingerprint, user_id) VALUES (2, 3, 3, '[[1,0,2],[0,0,3],[0,0,2]]', '` + string(m.Fingerprint) + `', 1)`,
+		`INSERT INTO matrices (id, width, height, clues, fingerprint, user_id) VALUES (3, 3, 2, '[[1,0,0],[0,0,1]]', '', 1)`,
+		`INSERT INTO matrices (id, width, height, clues, fingerprint, user_id) VALUES (4, 3, 2, '[[1,0,0],[0,0,1]]', '', 1)`,
+		`INSERT INTO users (id, nickname, email, password) VALUES (2, 'Other', 'other@gmail.com', 'password')`,
+		`INSERT INTO matrices (id, width, height, clues, fingerprint, user_id) VALUES (5, 3, 3, '[[1,0,2],[0,0,3],[0,0,2]]', '` + string(m.Fingerprint) + `', 2)`,
+	}
+	for _, step := range steps {
+		if err := db.Exec(step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, models.Migrate(db), nil)
+
+	samples := []struct {
+		id          uint64
+		fingerprint string
+	}{
+		{1, string(m.Fingerprint)},
+		{2, ""},
+		{3, ""},
+		{4, ""},
+		{5, string(m.Fingerprint)},
+	}
+	for _, v := range samples {
+		found, err := (&models.Matrix{}).FindMatrixByID(db, v.id)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, string(found.Fingerprint), v.fingerprint)
+	}
+
+	m.UserID = 1
+	m.Prepare()
+	existing, err := m.SaveMatrix(db)
+	assert.Equal(t, err, models.ErrDuplicateMatrix)
+	assert.Equal(t, existing.ID, uint64(1))
+}
@@ -0,0 +1,59 @@
+package matrixtests
+
+import (
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/daily"
+	"github.com/alcoccoque/puzzle-solver-go/api/models"
+	"github.com/alcoccoque/puzzle-solver-go/api/pool"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestPoolStore(t *testing.T) {
+	db := database(t)
+	assert.Equal(t, db.AutoMigrate(&models.PoolPuzzle{}).Error, nil)
+	store := models.PoolStore{DB: db}
+	bucket := pool.Bucket{Size: 3, Difficulty: "easy"}
+
+	puzzle := pool.Puzzle{Clues: clues, Solution: solution, Difficulty: "easy"}
+	assert.Equal(t, store.AddPuzzle(bucket, puzzle), nil)
+	count, err := store.CountPuzzles(bucket)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+
+	// The grids are kept as the same JSON text as a matrix's.
+	stored := models.PoolPuzzle{}
+	assert.Equal(t, db.Take(&stored).Error, nil)
+	assert.Equal(t, [][]int(stored.Grid), clues)
+	assert.Equal(t, [][]int(stored.Solution), solution)
+
+	taken, err := store.TakePuzzle(bucket)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, taken, puzzle)
+	_, err = store.TakePuzzle(bucket)
+	assert.Equal(t, err, pool.ErrEmpty)
+}
+
+func TestDailyStore(t *testing.T) {
+	db := database(t)
+	assert.Equal(t, db.AutoMigrate(&models.DailyPuzzle{}).Error, nil)
+	store := models.DailyStore{DB: db}
+	puzzle := daily.Puzzle{Date: "2020-01-02", Size: 3, Difficulty: "easy", Grid: clues}
+
+	saved, err := store.SaveDaily(puzzle)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Grid, clues)
+
+	found, err := store.FindDaily("2020-01-02")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, found, puzzle)
+
+	// A second server saving the same date gets the first puzzle back.
+	other := daily.Puzzle{Date: "2020-01-02", Size: 2, Difficulty: "easy", Grid: [][]int{{1, 0}, {0, 0}}}
+	saved, err = store.SaveDaily(other)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved, puzzle)
+
+	_, err = store.FindDaily("2020-01-03")
+	assert.Equal(t, err, daily.ErrNotFound)
+}
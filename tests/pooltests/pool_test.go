@@ -0,0 +1,96 @@
+package pooltests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/pool"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var (
+	easy   = pool.Bucket{Size: 4, Difficulty: solver.DifficultyEasy}
+	medium = pool.Bucket{Size: 4, Difficulty: solver.DifficultyMedium}
+)
+
+func rate(t *testing.T, grid [][]int) solver.Rating {
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rating, err := solver.NewPuzzleSolver(result["state"].(*solver.FieldState)).Rate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rating
+}
+
+func solve(t *testing.T, grid [][]int) [][]int {
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solved, err := solver.NewPuzzleSolver(result["state"].(*solver.FieldState)).Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return solved["solved_puzzle"].([][]int)
+}
+
+func TestRefill(t *testing.T) {
+	store := pool.NewMemoryStore()
+	p := pool.New(pool.Options{Buckets: []pool.Bucket{easy, medium}, Level: 2, Attempts: 200, Store: store})
+	assert.Equal(t, p.Refill(nil), nil)
+
+	stock, err := p.Stock()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stock, []pool.Stock{{Bucket: easy, Count: 2, Level: 2}, {Bucket: medium, Count: 2, Level: 2}})
+
+	for _, bucket := range []pool.Bucket{easy, medium} {
+		for i := 0; i < 2; i++ {
+			puzzle, err := p.Take(bucket)
+			assert.Equal(t, err, nil)
+			rating := rate(t, puzzle.Clues)
+			assert.Equal(t, rating.Solutions, 1)
+			assert.Equal(t, rating.Difficulty, bucket.Difficulty)
+			assert.Equal(t, puzzle.Difficulty, bucket.Difficulty)
+			assert.Equal(t, puzzle.Solution, solve(t, puzzle.Clues))
+		}
+		_, err := p.Take(bucket)
+		assert.Equal(t, err, pool.ErrEmpty)
+	}
+	_, err = p.Take(pool.Bucket{Size: 5, Difficulty: solver.DifficultyEasy})
+	assert.Equal(t, err, pool.ErrUnknownBucket)
+}
+
+func TestRefillCancel(t *testing.T) {
+	cancel := make(chan struct{})
+	close(cancel)
+	p := pool.New(pool.Options{Buckets: []pool.Bucket{easy}})
+	assert.Equal(t, p.Refill(cancel), solver.ErrCancelled)
+}
+
+// TestWorker takes puzzles from a started pool and waits for the worker to
+// replace them.
+func TestWorker(t *testing.T) {
+	store := pool.NewMemoryStore()
+	p := pool.New(pool.Options{Buckets: []pool.Bucket{easy}, Level: 1, Interval: time.Hour, Attempts: 200, Store: store})
+	p.Start()
+	defer p.Stop()
+
+	for i := 0; i < 3; i++ {
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			_, err := p.Take(easy)
+			if err == nil {
+				break
+			}
+			assert.Equal(t, err, pool.ErrEmpty)
+			if time.Now().After(deadline) {
+				t.Fatal("the worker did not refill the pool")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
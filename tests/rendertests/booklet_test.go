@@ -0,0 +1,68 @@
+package rendertests
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/render"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestPNG(t *testing.T) {
+	puzzle := state(t, [][]int{{3, 0, 1}, {0, 0, 0}, {2, 0, 3}})
+	board, err := render.NewBoard(puzzle, nil, render.ShowPuzzle)
+	assert.Equal(t, err, nil)
+
+	var out bytes.Buffer
+	err = render.PNG(&out, board, render.Options{CellSize: 20})
+	assert.Equal(t, err, nil)
+	img, err := png.Decode(&out)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, img.Bounds().Dx(), 80)
+	assert.Equal(t, img.Bounds().Dy(), 80)
+}
+
+func TestBooklet(t *testing.T) {
+	puzzle := state(t, [][]int{{3, 0, 1}, {0, 0, 0}, {2, 0, 3}})
+	solution := state(t, [][]int{{3, 3, 1}, {3, 2, 3}, {2, 2, 3}})
+
+	samples := []struct {
+		entries int
+		solved  int
+		perPage int
+		pages   int
+	}{
+		{1, 1, 0, 2},
+		{5, 5, 4, 4},
+		{5, 2, 4, 3},
+		{6, 0, 6, 1},
+	}
+	for _, v := range samples {
+		var entries []render.Entry
+		for i := 0; i < v.entries; i++ {
+			entry := render.Entry{Puzzle: puzzle}
+			if i < v.solved {
+				entry.Solution = solution
+			}
+			entries = append(entries, entry)
+		}
+		options := render.BookletOptions{PerPage: v.perPage, DPI: 30}
+
+		pages, err := render.BookletPNG(entries, options)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(pages), v.pages)
+
+		var out bytes.Buffer
+		err = render.BookletPDF(&out, entries, options)
+		assert.Equal(t, err, nil)
+		pdf := out.String()
+		assert.Equal(t, strings.HasPrefix(pdf, "%PDF-1.4"), true)
+		assert.Equal(t, strings.HasSuffix(pdf, "%%EOF\n"), true)
+		assert.Equal(t, strings.Count(pdf, "/Type /Page "), v.pages)
+	}
+
+	_, err := render.BookletPNG(nil, render.BookletOptions{})
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,80 @@
+package rendertests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/render"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func state(t *testing.T, grid [][]int) *solver.FieldState {
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result["state"].(*solver.FieldState)
+}
+
+func TestNewBoardBorders(t *testing.T) {
+	puzzle := state(t, [][]int{{3, 0, 1}, {0, 0, 0}, {2, 0, 3}})
+	solution := state(t, [][]int{{3, 3, 1}, {3, 2, 3}, {2, 2, 3}})
+
+	board, err := render.NewBoard(puzzle, solution, render.ShowSolution)
+	assert.Equal(t, err, nil)
+	samples := []struct {
+		right [][]bool
+		below [][]bool
+	}{
+		{
+			right: [][]bool{{false, true, false}, {true, true, false}, {false, true, false}},
+			below: [][]bool{{false, true, true}, {true, false, false}, {false, false, false}},
+		},
+	}
+	for _, v := range samples {
+		assert.Equal(t, board.Right, v.right)
+		assert.Equal(t, board.Below, v.below)
+	}
+	assert.Equal(t, board.Givens[0][0], true)
+	assert.Equal(t, board.Givens[0][1], false)
+
+	board, err = render.NewBoard(puzzle, nil, render.ShowPuzzle)
+	assert.Equal(t, err, nil)
+	for x := range board.Right {
+		for y := range board.Right[x] {
+			assert.Equal(t, board.Right[x][y], false)
+			assert.Equal(t, board.Below[x][y], false)
+		}
+	}
+
+	_, err = render.NewBoard(puzzle, nil, render.ShowSolution)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestSVG(t *testing.T) {
+	puzzle := state(t, [][]int{{3, 0, 1}, {0, 0, 0}, {2, 0, 3}})
+	solution := state(t, [][]int{{3, 3, 1}, {3, 2, 3}, {2, 2, 3}})
+
+	samples := []struct {
+		show  render.Show
+		texts int
+		bold  int
+	}{
+		{render.ShowPuzzle, 4, 4},
+		{render.ShowSolution, 9, 4},
+	}
+	for _, v := range samples {
+		board, err := render.NewBoard(puzzle, solution, v.show)
+		assert.Equal(t, err, nil)
+		var out bytes.Buffer
+		err = render.SVG(&out, board, render.Options{CellSize: 20})
+		assert.Equal(t, err, nil)
+		svg := out.String()
+		assert.Equal(t, strings.HasPrefix(svg, "<svg "), true)
+		assert.Equal(t, strings.Contains(svg, `width="80"`), true)
+		assert.Equal(t, strings.Count(svg, "<text "), v.texts)
+		assert.Equal(t, strings.Count(svg, `font-weight="bold"`), v.bold)
+	}
+}
@@ -0,0 +1,51 @@
+package solvertests
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// corpusPath holds puzzles with verified unique solutions, one JSON object
+// per line. The file is also a valid batch corpus.
+const corpusPath = "testdata/corpus.jsonl"
+
+type corpusPuzzle struct {
+	Name         string  `json:"name"`
+	Grid         [][]int `json:"grid"`
+	Solution     [][]int `json:"solution"`
+	Difficulty   string  `json:"difficulty"`
+	ExpectedHash string  `json:"expected_hash"`
+}
+
+func loadCorpus(tb testing.TB) []corpusPuzzle {
+	file, err := os.Open(corpusPath)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer file.Close()
+	var puzzles []corpusPuzzle
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var p corpusPuzzle
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			tb.Fatal(err)
+		}
+		puzzles = append(puzzles, p)
+	}
+	if err := scanner.Err(); err != nil {
+		tb.Fatal(err)
+	}
+	return puzzles
+}
+
+func toState(tb testing.TB, grid [][]int) *solver.FieldState {
+	result, err := solver.FromListToState(grid)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return result["state"].(*solver.FieldState)
+}
@@ -0,0 +1,113 @@
+package solvertests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// gridFromBytes turns fuzz input into a grid of any shape: 0xff starts a
+// new row and every other byte is a cell value, read as signed so negative
+// values are covered too.
+func gridFromBytes(data []byte) [][]int {
+	grid := [][]int{{}}
+	for _, b := range data {
+		if b == 0xff {
+			grid = append(grid, []int{})
+			continue
+		}
+		row := len(grid) - 1
+		grid[row] = append(grid[row], int(int8(b)))
+	}
+	return grid
+}
+
+func FuzzFromListToState(f *testing.F) {
+	f.Add([]byte{1, 0, 0xff, 0, 0})
+	f.Add([]byte{3, 0, 1, 0xff, 0, 0, 0, 0xff, 2, 0, 3})
+	f.Add([]byte{1, 1, 0xff, 0})
+	f.Add([]byte{0x80, 0, 0xff, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		grid := gridFromBytes(data)
+		result, err := solver.FromListToState(grid)
+		if err != nil {
+			return
+		}
+		state := result["state"].(*solver.FieldState)
+		list := state.ToList()
+		for x := range grid {
+			for y := range grid[x] {
+				if list[x][y] != grid[x][y] {
+					t.Fatalf("cell %d,%d is %d, expected %d", x, y, list[x][y], grid[x][y])
+				}
+			}
+		}
+	})
+}
+
+func FuzzSolve(f *testing.F) {
+	f.Add([]byte{1, 0, 0xff, 0, 0})
+	f.Add([]byte{3, 0, 1, 0xff, 0, 0, 0, 0xff, 2, 0, 3})
+	f.Add([]byte{2, 2, 2, 0xff, 0, 0, 0, 0xff, 0, 0, 0})
+	f.Add([]byte{4, 2, 1, 2, 0xff, 0, 0, 0, 0, 0xff, 0, 3, 3, 1, 0xff, 0, 1, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		grid := gridFromBytes(data)
+		if len(grid) > 6 {
+			return
+		}
+		result, err := solver.FromListToState(grid)
+		if err != nil {
+			return
+		}
+		state := result["state"].(*solver.FieldState)
+
+		cancel := make(chan struct{})
+		timer := time.AfterFunc(time.Second, func() { close(cancel) })
+		defer timer.Stop()
+		ps := solver.NewPuzzleSolver(state)
+		ps.SetOptions(solver.SolveOptions{Cancel: cancel})
+		_, err = ps.Solve()
+		if err == solver.ErrCancelled {
+			return
+		}
+		solved := err == nil
+		if solved {
+			checkSolution(t, grid, state)
+		}
+
+		// The other engines must agree on whether there is a solution.
+		for _, mode := range modes[1:] {
+			if len(grid) > 4 && mode.options.Mode == solver.ModeExactCover {
+				continue
+			}
+			options := mode.options
+			options.Cancel = cancel
+			ps := solver.NewPuzzleSolver(toState(t, grid))
+			ps.SetOptions(options)
+			count, err := ps.CountSolutions(1)
+			if err == solver.ErrCancelled {
+				return
+			}
+			if (count == 1) != solved {
+				t.Fatalf("%s mode finds %d solutions of %v, solved: %v (%v)", mode.name, count, grid, solved, err)
+			}
+		}
+	})
+}
+
+// checkSolution fails unless the state is a valid solution that keeps
+// every given of the grid.
+func checkSolution(t *testing.T, grid [][]int, state *solver.FieldState) {
+	if !state.IsSolved() {
+		t.Fatalf("%v is not a valid solution of %v", state.ToList(), grid)
+	}
+	list := state.ToList()
+	for x := range grid {
+		for y := range grid[x] {
+			if grid[x][y] != 0 && list[x][y] != grid[x][y] {
+				t.Fatalf("given %d at %d,%d became %d", grid[x][y], x, y, list[x][y])
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package solvertests
+
+import (
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// TestSymmetricGenerator checks that symmetric layouts keep their clue
+// positions under the symmetry's transforms and stay unique.
+func TestSymmetricGenerator(t *testing.T) {
+	samples := []struct {
+		symmetry   solver.ClueSymmetry
+		transforms []solver.Symmetry
+	}{
+		{solver.SymmetryNone, nil},
+		{solver.SymmetryRotate180, []solver.Symmetry{solver.Rotate180}},
+		{solver.SymmetryRotate90, []solver.Symmetry{solver.Rotate90, solver.Rotate180}},
+		{solver.SymmetryHorizontal, []solver.Symmetry{solver.FlipHorizontal}},
+		{solver.SymmetryVertical, []solver.Symmetry{solver.FlipVertical}},
+		{solver.SymmetryDiagonal, []solver.Symmetry{solver.Transpose}},
+	}
+	for _, v := range samples {
+		for size := 4; size <= 6; size++ {
+			generator := solver.NewPuzzleGenerator(size)
+			generator.SetSymmetry(v.symmetry)
+			grid, err := generator.GeneratePuzzle(0.3)
+			assert.Equal(t, err, nil)
+
+			state := toState(t, grid)
+			for _, s := range v.transforms {
+				moved := state.Transform(s)
+				for x := 0; x < size; x++ {
+					for y := 0; y < size; y++ {
+						given := grid[x][y] != 0
+						assert.Equal(t, moved.GetState(solver.Cell{X: x, Y: y}) != 0, given)
+					}
+				}
+			}
+			count, err := solver.NewPuzzleSolver(state).CountSolutions(2)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, count, 1)
+		}
+	}
+}
+
+func TestGeneratorCancel(t *testing.T) {
+	cancel := make(chan struct{})
+	close(cancel)
+	template := [][]int{{-1, 0, 0, -1}, {0, 0, 0, 0}, {0, 0, 0, 0}, {-1, 0, 0, -1}}
+	samples := []func(*solver.PuzzleGenerator) error{
+		func(generator *solver.PuzzleGenerator) error {
+			_, err := generator.GeneratePuzzle(0.3)
+			return err
+		},
+		func(generator *solver.PuzzleGenerator) error {
+			_, err := generator.GenerateFromTemplate(template, 10)
+			return err
+		},
+		func(generator *solver.PuzzleGenerator) error {
+			_, err := generator.GenerateRated(solver.DifficultyHard, 10)
+			return err
+		},
+	}
+	for _, generate := range samples {
+		generator := solver.NewPuzzleGenerator(4)
+		generator.SetCancel(cancel)
+		assert.Equal(t, generate(generator), solver.ErrCancelled)
+	}
+}
+
+func TestGeneratorProgress(t *testing.T) {
+	for _, symmetry := range []solver.ClueSymmetry{solver.SymmetryNone, solver.SymmetryRotate180} {
+		done, total := -1, 0
+		generator := solver.NewPuzzleGenerator(5)
+		generator.SetSymmetry(symmetry)
+		generator.SetProgress(func(d, n int) {
+			done, total = d, n
+		})
+		_, err := generator.GeneratePuzzle(0.3)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, done, total)
+	}
+}
+
+func TestGenerateRated(t *testing.T) {
+	for _, difficulty := range []string{solver.DifficultyEasy, solver.DifficultyMedium, solver.DifficultyHard} {
+		grid, err := solver.NewPuzzleGenerator(4).GenerateRated(difficulty, 200)
+		assert.Equal(t, err, nil)
+		rating, err := solver.NewPuzzleSolver(toState(t, grid)).Rate()
+		assert.Equal(t, err, nil)
+		assert.Equal(t, rating.Solutions, 1)
+		assert.Equal(t, rating.Difficulty, difficulty)
+	}
+
+	_, err := solver.NewPuzzleGenerator(4).GenerateRated("tricky", 1)
+	assert.Equal(t, err.Error(), `unknown difficulty "tricky"`)
+}
@@ -0,0 +1,68 @@
+package solvertests
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// randomClues is a small board with a few random givens, most of them
+// unsolvable or with many solutions.
+type randomClues [][]int
+
+func (randomClues) Generate(r *rand.Rand, _ int) reflect.Value {
+	size := r.Intn(4) + 2
+	grid := make([][]int, size)
+	for x := range grid {
+		grid[x] = make([]int, size)
+		for y := range grid[x] {
+			if r.Intn(3) == 0 {
+				grid[x][y] = r.Intn(size) + 1
+			}
+		}
+	}
+	return reflect.ValueOf(randomClues(grid))
+}
+
+// TestSolveProperty checks that whatever Solve returns without an error is
+// a valid solution keeping every given, and that counting agrees.
+func TestSolveProperty(t *testing.T) {
+	property := func(clues randomClues) bool {
+		grid := [][]int(clues)
+		state := toState(t, grid)
+		_, err := solver.NewPuzzleSolver(state).Solve()
+		count, countErr := solver.NewPuzzleSolver(toState(t, grid)).CountSolutions(1)
+		if err != nil {
+			return countErr != nil || count == 0
+		}
+		checkSolution(t, grid, state)
+		return countErr == nil && count == 1
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 300}); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestGeneratorProperty checks that generated puzzles are solved back to a
+// valid grid that keeps their givens.
+func TestGeneratorProperty(t *testing.T) {
+	property := func(seed int64) bool {
+		size := int(uint64(seed)%4) + 3
+		grid, err := solver.NewPuzzleGenerator(size).GeneratePuzzle(0.4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := toState(t, grid)
+		if _, err := solver.NewPuzzleSolver(state).Solve(); err != nil {
+			t.Fatalf("generated puzzle %v has no solution: %v", grid, err)
+		}
+		checkSolution(t, grid, state)
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+		t.Error(err)
+	}
+}
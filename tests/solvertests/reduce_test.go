@@ -0,0 +1,64 @@
+package solvertests
+
+import (
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func countSolutions(t *testing.T, grid [][]int) int {
+	count, err := solver.NewPuzzleSolver(toState(t, grid)).CountSolutions(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// TestReduce reduces the solutions of the smaller corpus puzzles and checks
+// that the result is unique, keeps the pinned given and cannot lose any
+// other given.
+func TestReduce(t *testing.T) {
+	pin := solver.Cell{X: 0, Y: 0}
+	for _, p := range loadCorpus(t) {
+		if len(p.Grid) > 6 {
+			continue
+		}
+		reduced, err := solver.Reduce(toState(t, p.Solution), solver.ReduceOptions{Pinned: []solver.Cell{pin}})
+		assert.Equal(t, err, nil)
+		grid := reduced.ToList()
+		assert.Equal(t, countSolutions(t, grid), 1)
+		assert.Equal(t, grid[pin.X][pin.Y], p.Solution[pin.X][pin.Y])
+		for x, row := range grid {
+			for y, value := range row {
+				if value == 0 || (solver.Cell{X: x, Y: y}) == pin {
+					continue
+				}
+				assert.Equal(t, value, p.Solution[x][y])
+				grid[x][y] = 0
+				assert.Equal(t, countSolutions(t, grid), 2)
+				grid[x][y] = value
+			}
+		}
+	}
+}
+
+func TestReducePrefer(t *testing.T) {
+	grid := [][]int{{1, 2, 2}, {3, 3, 3}, {1, 2, 2}}
+	samples := []struct {
+		prefer   []int
+		expected [][]int
+	}{
+		{nil, [][]int{{0, 0, 2}, {0, 0, 3}, {0, 0, 2}}},
+		{[]int{2}, [][]int{{1, 0, 0}, {0, 3, 3}, {1, 0, 0}}},
+		{[]int{3, 2}, [][]int{{1, 0, 2}, {0, 0, 0}, {1, 0, 2}}},
+	}
+	for _, v := range samples {
+		reduced, err := solver.Reduce(toState(t, grid), solver.ReduceOptions{Prefer: v.prefer})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, reduced.ToList(), v.expected)
+	}
+
+	_, err := solver.Reduce(toState(t, [][]int{{0, 0}, {0, 0}}), solver.ReduceOptions{})
+	assert.Equal(t, err, solver.ErrNotUnique)
+}
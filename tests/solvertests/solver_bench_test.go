@@ -0,0 +1,44 @@
+package solvertests
+
+import (
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+)
+
+// BenchmarkSolve solves every corpus puzzle in every mode. Compare two
+// commits with cmd/benchcompare.
+func BenchmarkSolve(b *testing.B) {
+	puzzles := loadCorpus(b)
+	for _, mode := range modes {
+		for _, p := range puzzles {
+			if mode.maxSize != 0 && len(p.Grid) > mode.maxSize {
+				continue
+			}
+			b.Run(mode.name+"/"+p.Name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					ps := solver.NewPuzzleSolver(toState(b, p.Grid))
+					ps.SetOptions(mode.options)
+					if _, err := ps.Solve(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkUnique measures the uniqueness check the generator relies on.
+func BenchmarkUnique(b *testing.B) {
+	for _, p := range loadCorpus(b) {
+		b.Run(p.Name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ps := solver.NewPuzzleSolver(toState(b, p.Grid))
+				ps.SetOptions(solver.SolveOptions{Mode: solver.ModeRegions})
+				if count, err := ps.CountSolutions(2); err != nil || count != 1 {
+					b.Fatalf("%d solutions, %v", count, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package solvertests
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/batch"
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var modes = []struct {
+	name    string
+	options solver.SolveOptions
+	// maxSize leaves out boards the mode is not meant for.
+	maxSize int
+}{
+	{"cells", solver.SolveOptions{}, 0},
+	{"regions", solver.SolveOptions{Mode: solver.ModeRegions}, 0},
+	{"parallel", solver.SolveOptions{Workers: 4}, 0},
+	{"exactcover", solver.SolveOptions{Mode: solver.ModeExactCover}, 7},
+}
+
+func TestFromListToState(t *testing.T) {
+	samples := []struct {
+		grid   [][]int
+		failed bool
+	}{
+		{[][]int{}, true},
+		{[][]int{{1}}, true},
+		{[][]int{{1, 0}, {0, 2}}, false},
+		{[][]int{{3, 0, 1}, {0, 0, 0}, {2, 0, 3}}, false},
+	}
+	for _, v := range samples {
+		result, err := solver.FromListToState(v.grid)
+		assert.Equal(t, err != nil, v.failed)
+		if !v.failed {
+			assert.Equal(t, result["state"].(*solver.FieldState).ToList(), v.grid)
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	samples := []struct {
+		grid     [][]int
+		solution [][]int
+	}{
+		{[][]int{{1, 0}, {0, 0}}, [][]int{{1, 3}, {3, 3}}},
+		{[][]int{{0, 0}, {0, 4}}, [][]int{{4, 4}, {4, 4}}},
+		{[][]int{{1, 0}, {0, 1}}, nil},
+		{[][]int{{4, 2, 1, 2}, {0, 0, 0, 0}, {0, 3, 3, 1}, {0, 1, 0, 0}}, [][]int{{4, 2, 1, 2}, {4, 2, 3, 2}, {4, 3, 3, 1}, {4, 1, 2, 2}}},
+	}
+	for _, mode := range modes {
+		for _, v := range samples {
+			state := toState(t, v.grid)
+			ps := solver.NewPuzzleSolver(state)
+			ps.SetOptions(mode.options)
+			result, err := ps.Solve()
+			if v.solution == nil {
+				assert.NotEqual(t, err, nil)
+				continue
+			}
+			assert.Equal(t, err, nil)
+			assert.Equal(t, result["solved_puzzle"], v.solution)
+		}
+	}
+}
+
+func TestCountSolutions(t *testing.T) {
+	samples := []struct {
+		grid  [][]int
+		limit int
+		count int
+	}{
+		{[][]int{{0, 0}, {0, 0}}, 10, 5},
+		{[][]int{{0, 0}, {0, 0}}, 3, 3},
+		{[][]int{{1, 0}, {0, 0}}, 10, 1},
+		{[][]int{{1, 0}, {0, 1}}, 10, 0},
+		{[][]int{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}}, 1000, 0},
+	}
+	// The empty 3x3 board is counted once here and checked against every
+	// mode below.
+	empty := solver.NewPuzzleSolver(toState(t, samples[4].grid))
+	samples[4].count, _ = empty.CountSolutions(1000)
+	assert.NotEqual(t, samples[4].count, 0)
+
+	for _, mode := range modes {
+		for _, v := range samples {
+			ps := solver.NewPuzzleSolver(toState(t, v.grid))
+			ps.SetOptions(mode.options)
+			count, err := ps.CountSolutions(v.limit)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, count, v.count)
+		}
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	samples := []struct {
+		grid      [][]int
+		conflicts int
+	}{
+		{[][]int{{1, 3}, {3, 3}}, 0},
+		{[][]int{{1, 0}, {0, 0}}, 0},
+		{[][]int{{1, 1}, {0, 0}}, 2},
+		{[][]int{{2, 1}, {1, 0}}, 1},
+		{[][]int{{2, 1}, {1, 3}}, 2},
+		{[][]int{{2, 2, 2}, {0, 0, 0}, {0, 0, 0}}, 3},
+	}
+	for _, v := range samples {
+		assert.Equal(t, len(toState(t, v.grid).Conflicts()), v.conflicts)
+	}
+}
+
+func TestOversizedGivens(t *testing.T) {
+	samples := []struct {
+		grid [][]int
+		err  string
+	}{
+		{[][]int{{1, 1}, {0, 0}}, "region of 1 at 0,0 has 2 cells"},
+		{[][]int{{0, 2, 2}, {0, 0, 2}, {0, 0, 0}}, "region of 2 at 0,1 has 3 cells"},
+	}
+	for _, v := range samples {
+		_, err := solver.NewPuzzleSolver(toState(t, v.grid)).Solve()
+		assert.NotEqual(t, err, nil)
+		assert.Equal(t, err.Error(), v.err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	cancel := make(chan struct{})
+	close(cancel)
+	for _, mode := range modes {
+		options := mode.options
+		options.Cancel = cancel
+		ps := solver.NewPuzzleSolver(toState(t, [][]int{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}}))
+		ps.SetOptions(options)
+		_, err := ps.CountSolutions(1000)
+		assert.Equal(t, err, solver.ErrCancelled)
+	}
+}
+
+func TestExactCoverLimits(t *testing.T) {
+	emptyState := func(size int) *solver.FieldState {
+		grid := make([][]int, size)
+		for i := range grid {
+			grid[i] = make([]int, size)
+		}
+		return toState(t, grid)
+	}
+
+	ps := solver.NewPuzzleSolver(emptyState(11))
+	ps.SetOptions(solver.SolveOptions{Mode: solver.ModeExactCover})
+	_, err := ps.CountSolutions(1)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, err.Error(), "exact cover mode supports boards up to 10x10, got 11x11")
+
+	// Listing the placements of an empty 10x10 board takes a while; a
+	// cancel on the way must stop it.
+	cancel := make(chan struct{})
+	time.AfterFunc(20*time.Millisecond, func() { close(cancel) })
+	ps = solver.NewPuzzleSolver(emptyState(10))
+	ps.SetOptions(solver.SolveOptions{Mode: solver.ModeExactCover, Cancel: cancel})
+	_, err = ps.CountSolutions(1)
+	assert.Equal(t, err, solver.ErrCancelled)
+}
+
+// TestCancelSetup checks that a cancelled solve on a large empty board
+// stops before working out the possible values of every cell.
+func TestCancelSetup(t *testing.T) {
+	grid := make([][]int, 40)
+	for i := range grid {
+		grid[i] = make([]int, 40)
+	}
+	cancel := make(chan struct{})
+	close(cancel)
+	ps := solver.NewPuzzleSolver(toState(t, grid))
+	ps.SetOptions(solver.SolveOptions{Cancel: cancel})
+	start := time.Now()
+	_, err := ps.Solve()
+	assert.Equal(t, err, solver.ErrCancelled)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("cancelled solve took %v", elapsed)
+	}
+}
+
+// TestCorpus checks that every corpus puzzle has exactly the recorded
+// solution and that every mode finds it. Boards above 8x8 are left out of
+// short runs.
+func TestCorpus(t *testing.T) {
+	for _, p := range loadCorpus(t) {
+		if testing.Short() && len(p.Grid) > 8 {
+			continue
+		}
+		assert.Equal(t, len(toState(t, p.Solution).Conflicts()), 0)
+		assert.Equal(t, batch.Hash(p.Solution), p.ExpectedHash)
+
+		count, err := solver.NewPuzzleSolver(toState(t, p.Grid)).CountSolutions(2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, count, 1)
+
+		for _, mode := range modes {
+			if mode.maxSize != 0 && len(p.Grid) > mode.maxSize {
+				continue
+			}
+			state := toState(t, p.Grid)
+			ps := solver.NewPuzzleSolver(state)
+			ps.SetOptions(mode.options)
+			if _, err := ps.Solve(); err != nil {
+				t.Fatalf("%s in %s mode: %v", p.Name, mode.name, err)
+			}
+			assert.Equal(t, state.ToList(), p.Solution)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	for _, p := range loadCorpus(t) {
+		if testing.Short() && len(p.Grid) > 8 {
+			continue
+		}
+		rating, err := solver.NewPuzzleSolver(toState(t, p.Grid)).Rate()
+		assert.Equal(t, err, nil)
+		assert.Equal(t, rating.Solutions, 1)
+		assert.Equal(t, rating.Difficulty, p.Difficulty)
+	}
+
+	rating, err := solver.NewPuzzleSolver(toState(t, [][]int{{0, 0}, {0, 0}})).Rate()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rating.Solutions, 2)
+	assert.Equal(t, rating.Difficulty, "")
+
+	cancel := make(chan struct{})
+	close(cancel)
+	ps := solver.NewPuzzleSolver(toState(t, [][]int{{0, 0}, {0, 0}}))
+	ps.SetOptions(solver.SolveOptions{Cancel: cancel})
+	_, err = ps.Rate()
+	assert.Equal(t, err, solver.ErrCancelled)
+}
+
+// TestProgress proves the uniqueness of the corpus puzzle with the largest
+// search tree and checks that progress is reported along the way.
+func TestProgress(t *testing.T) {
+	var grid [][]int
+	most := 0
+	for _, p := range loadCorpus(t) {
+		if testing.Short() && len(p.Grid) > 8 {
+			continue
+		}
+		rating, err := solver.NewPuzzleSolver(toState(t, p.Grid)).Rate()
+		assert.Equal(t, err, nil)
+		if rating.Nodes > most {
+			grid, most = p.Grid, rating.Nodes
+		}
+	}
+	if most < 1024 {
+		t.Skip("no corpus puzzle needs enough nodes")
+	}
+
+	calls, deepest := 0, 0
+	ps := solver.NewPuzzleSolver(toState(t, grid))
+	ps.SetOptions(solver.SolveOptions{Progress: func(nodes, depth int) {
+		calls++
+		assert.Equal(t, nodes, calls*1024)
+		if depth > deepest {
+			deepest = depth
+		}
+	}})
+	_, err := ps.CountSolutions(2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, calls, most/1024)
+	assert.NotEqual(t, deepest, 0)
+}
+
+// TestTrace replays the traced moves over the puzzle and expects to end on
+// the solution, with every undo taking back the latest assign of its cell.
+func TestTrace(t *testing.T) {
+	for _, p := range loadCorpus(t) {
+		if len(p.Grid) > 6 {
+			continue
+		}
+		for _, mode := range []solver.SearchMode{solver.ModeCells, solver.ModeRegions} {
+			board := make([][]int, len(p.Grid))
+			empty := 0
+			for x, row := range p.Grid {
+				board[x] = append([]int(nil), row...)
+				for _, value := range row {
+					if value == 0 {
+						empty++
+					}
+				}
+			}
+			assigns := 0
+			ps := solver.NewPuzzleSolver(toState(t, p.Grid))
+			ps.SetOptions(solver.SolveOptions{Mode: mode, Trace: func(event solver.TraceEvent) {
+				switch event.Kind {
+				case solver.TraceAssign:
+					assert.Equal(t, board[event.Cell.X][event.Cell.Y], 0)
+					board[event.Cell.X][event.Cell.Y] = event.Value
+					assigns++
+				case solver.TraceUndo:
+					assert.Equal(t, board[event.Cell.X][event.Cell.Y], event.Value)
+					board[event.Cell.X][event.Cell.Y] = 0
+				}
+				assert.NotEqual(t, event.Depth, 0)
+			}})
+			solved, err := ps.Solve()
+			assert.Equal(t, err, nil)
+			assert.Equal(t, board, solved["solved_puzzle"])
+			assert.Equal(t, assigns >= empty, true)
+		}
+	}
+}
+
+// TestParallelSeed expects the same seed to find the same solution of an
+// open board whatever the number of workers.
+func TestParallelSeed(t *testing.T) {
+	grid := [][]int{{0, 0, 0, 0, 0}, {0, 0, 0, 0, 0}, {0, 0, 0, 0, 0}, {0, 0, 0, 0, 0}, {0, 0, 0, 0, 0}}
+	found := map[string]bool{}
+	for seed := int64(1); seed <= 5; seed++ {
+		var solutions []interface{}
+		for _, workers := range []int{1, 4} {
+			ps := solver.NewPuzzleSolver(toState(t, grid))
+			ps.SetOptions(solver.SolveOptions{ValueOrder: solver.ValueRandom, Seed: seed, Workers: workers})
+			solved, err := ps.Solve()
+			assert.Equal(t, err, nil)
+			solutions = append(solutions, solved["solved_puzzle"])
+		}
+		assert.Equal(t, solutions[1], solutions[0])
+		found[fmt.Sprint(solutions[0])] = true
+	}
+	assert.NotEqual(t, len(found), 1)
+}
+
+// TestParallelDepth expects the branches of a parallel search to count
+// depths from the root, so the last move on the way to a solution of an
+// empty board lies as deep as there are cells.
+func TestParallelDepth(t *testing.T) {
+	grid := [][]int{{0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}}
+	var mu sync.Mutex
+	deepest := 0
+	ps := solver.NewPuzzleSolver(toState(t, grid))
+	ps.SetOptions(solver.SolveOptions{Workers: 4, Trace: func(event solver.TraceEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if event.Depth > deepest {
+			deepest = event.Depth
+		}
+	}})
+	_, err := ps.Solve()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, deepest, 16)
+}
@@ -0,0 +1,54 @@
+package solvertests
+
+import (
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestTransform(t *testing.T) {
+	grid := [][]int{{1, 2, 0}, {0, 3, 0}, {0, 0, 4}}
+	samples := []struct {
+		symmetry solver.Symmetry
+		expected [][]int
+	}{
+		{solver.Identity, [][]int{{1, 2, 0}, {0, 3, 0}, {0, 0, 4}}},
+		{solver.Rotate90, [][]int{{0, 0, 1}, {0, 3, 2}, {4, 0, 0}}},
+		{solver.Rotate180, [][]int{{4, 0, 0}, {0, 3, 0}, {0, 2, 1}}},
+		{solver.Rotate270, [][]int{{0, 0, 4}, {2, 3, 0}, {1, 0, 0}}},
+		{solver.FlipHorizontal, [][]int{{0, 0, 4}, {0, 3, 0}, {1, 2, 0}}},
+		{solver.FlipVertical, [][]int{{0, 2, 1}, {0, 3, 0}, {4, 0, 0}}},
+		{solver.Transpose, [][]int{{1, 0, 0}, {2, 3, 0}, {0, 0, 4}}},
+		{solver.AntiTranspose, [][]int{{4, 0, 0}, {0, 3, 2}, {0, 0, 1}}},
+	}
+	for _, v := range samples {
+		assert.Equal(t, toState(t, grid).Transform(v.symmetry).ToList(), v.expected)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	for _, p := range loadCorpus(t) {
+		state := toState(t, p.Grid)
+		fingerprint := state.Fingerprint()
+		canonical, symmetry := state.Canonical()
+		assert.Equal(t, state.Transform(symmetry).ToList(), canonical.ToList())
+		for _, s := range solver.Symmetries {
+			assert.Equal(t, state.Transform(s).Fingerprint(), fingerprint)
+		}
+	}
+
+	samples := []struct {
+		a, b [][]int
+		same bool
+	}{
+		{[][]int{{1, 0}, {0, 0}}, [][]int{{0, 0}, {0, 1}}, true},
+		{[][]int{{1, 0}, {0, 0}}, [][]int{{2, 0}, {0, 0}}, false},
+		{[][]int{{1, 2}, {0, 0}}, [][]int{{1, 0}, {0, 2}}, false},
+		{[][]int{{0, 0}, {0, 0}}, [][]int{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}}, false},
+	}
+	for _, v := range samples {
+		same := toState(t, v.a).Fingerprint() == toState(t, v.b).Fingerprint()
+		assert.Equal(t, same, v.same)
+	}
+}
@@ -0,0 +1,62 @@
+package solvertests
+
+import (
+	"testing"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/solver"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// TestGenerateFromTemplate uses the clue layouts of the 4x4 corpus puzzles
+// as templates, with the first clue's value fixed.
+func TestGenerateFromTemplate(t *testing.T) {
+	for _, p := range loadCorpus(t) {
+		if len(p.Grid) > 4 {
+			continue
+		}
+		template := make([][]int, len(p.Grid))
+		fixed := solver.Cell{X: -1}
+		for x, row := range p.Grid {
+			template[x] = make([]int, len(row))
+			for y, value := range row {
+				switch {
+				case value == 0:
+				case fixed.X == -1:
+					fixed = solver.Cell{X: x, Y: y}
+					template[x][y] = value
+				default:
+					template[x][y] = solver.TemplateClue
+				}
+			}
+		}
+
+		grid, err := solver.NewPuzzleGenerator(len(template)).GenerateFromTemplate(template, 500)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, grid[fixed.X][fixed.Y], p.Grid[fixed.X][fixed.Y])
+		for x, row := range grid {
+			for y, value := range row {
+				assert.Equal(t, value != 0, template[x][y] != solver.TemplateEmpty)
+			}
+		}
+		assert.Equal(t, countSolutions(t, grid), 1)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	samples := []struct {
+		template [][]int
+		err      string
+	}{
+		{[][]int{{-1, 0}, {0, 0}}, "template has 2 rows, expected 3"},
+		{[][]int{{-1, 0, 0}, {0, 0}, {0, 0, 0}}, "template row 1 has 2 cells, expected 3"},
+		{[][]int{{-2, 0, 0}, {0, 0, 0}, {0, 0, 0}}, "bad template value -2 at 0,0"},
+		{[][]int{{0, 0, 0}, {0, 10, 0}, {0, 0, 0}}, "bad template value 10 at 1,1"},
+		{[][]int{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}}, "template has no clues"},
+		{[][]int{{1, 0, 0}, {0, 0, 0}, {0, 0, 0}}, "no solution fits the template: puzzle is unsolvable"},
+	}
+	for _, v := range samples {
+		_, err := solver.NewPuzzleGenerator(3).GenerateFromTemplate(v.template, 5)
+		assert.NotEqual(t, err, nil)
+		assert.Equal(t, err.Error(), v.err)
+	}
+}
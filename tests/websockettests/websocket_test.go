@@ -0,0 +1,284 @@
+package websockettests
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alcoccoque/puzzle-solver-go/api/websocket"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// client is the far end of a connection, speaking just enough of the
+// protocol to drive the server.
+type client struct {
+	conn net.Conn
+	in   *bufio.Reader
+}
+
+func dial(t *testing.T, server *httptest.Server) *client {
+	c, response := handshake(t, server, "")
+	assert.Equal(t, response.StatusCode, http.StatusSwitchingProtocols)
+	assert.Equal(t, response.Header.Get("Sec-WebSocket-Accept"), websocket.AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+	return c
+}
+
+// handshake sends an opening handshake, with an Origin header unless
+// origin is empty, and reads the response.
+func handshake(t *testing.T, server *httptest.Server, origin string) (*client, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	headers := ""
+	if origin != "" {
+		headers = "Origin: " + origin + "\r\n"
+	}
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n%s\r\n", key, headers)
+	in := bufio.NewReader(conn)
+	response, err := http.ReadResponse(in, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &client{conn: conn, in: in}, response
+}
+
+// send writes a single final masked frame.
+func (c *client) send(opcode byte, payload []byte) {
+	c.conn.Write(frame(0x80|opcode, payload))
+}
+
+// frame encodes a masked frame whose first byte holds the final bit, the
+// reserved bits and the opcode.
+func frame(first byte, payload []byte) []byte {
+	mask := []byte{1, 2, 3, 4}
+	header := []byte{first, 0x80 | byte(len(payload))}
+	if len(payload) > 125 {
+		header[1] = 0x80 | 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	}
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+	return append(append(header, mask...), masked...)
+}
+
+func (c *client) receive(t *testing.T) (byte, []byte) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.in, header); err != nil {
+		t.Fatal(err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		extended := make([]byte, 2)
+		io.ReadFull(c.in, extended)
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.in, payload); err != nil {
+		t.Fatal(err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+// echo answers every message with its upper-case form until the client
+// closes.
+func echo(t *testing.T) *httptest.Server {
+	return echoFrom(t, websocket.Options{})
+}
+
+func echoFrom(t *testing.T, options websocket.Options) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, options)
+		if err != nil {
+			return
+		}
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteText([]byte(strings.ToUpper(string(message))))
+		}
+	}))
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The sample handshake of RFC 6455, section 1.3.
+	assert.Equal(t, websocket.AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+}
+
+func TestUpgradeRejected(t *testing.T) {
+	server := echo(t)
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, response.StatusCode, http.StatusUpgradeRequired)
+}
+
+func TestUpgradeOrigin(t *testing.T) {
+	samples := []struct {
+		origins    []string
+		origin     string
+		statusCode int
+	}{
+		{nil, "", http.StatusSwitchingProtocols},
+		{nil, "http://test", http.StatusSwitchingProtocols},
+		{nil, "https://evil.example", http.StatusForbidden},
+		{[]string{"https://app.example"}, "https://app.example", http.StatusSwitchingProtocols},
+		{[]string{"https://app.example"}, "http://test", http.StatusForbidden},
+		{[]string{"https://app.example"}, "", http.StatusSwitchingProtocols},
+	}
+	for _, v := range samples {
+		server := echoFrom(t, websocket.Options{Origins: v.origins})
+		c, response := handshake(t, server, v.origin)
+		assert.Equal(t, response.StatusCode, v.statusCode)
+		c.conn.Close()
+		server.Close()
+	}
+}
+
+func TestMessages(t *testing.T) {
+	server := echo(t)
+	defer server.Close()
+	c := dial(t, server)
+	defer c.conn.Close()
+
+	c.send(0x1, []byte("hello"))
+	opcode, payload := c.receive(t)
+	assert.Equal(t, opcode, byte(0x1))
+	assert.Equal(t, string(payload), "HELLO")
+
+	c.send(0x9, []byte("ping"))
+	opcode, payload = c.receive(t)
+	assert.Equal(t, opcode, byte(0xA))
+	assert.Equal(t, string(payload), "ping")
+
+	// A fragmented message: a non-final text frame and a continuation.
+	c.conn.Write([]byte{0x01, 0x82, 0, 0, 0, 0, 'a', 'b'})
+	c.send(0x0, []byte("c"))
+	_, payload = c.receive(t)
+	assert.Equal(t, string(payload), "ABC")
+
+	long := strings.Repeat("a", 300)
+	c.conn.Write([]byte{0x81, 0xFE, 0x01, 0x2C, 0, 0, 0, 0})
+	c.conn.Write([]byte(long))
+	_, payload = c.receive(t)
+	assert.Equal(t, string(payload), strings.ToUpper(long))
+
+	c.send(0x8, []byte{0x03, 0xE8})
+	opcode, payload = c.receive(t)
+	assert.Equal(t, opcode, byte(0x8))
+	assert.Equal(t, binary.BigEndian.Uint16(payload), uint16(websocket.CloseNormal))
+}
+
+func TestProtocolErrors(t *testing.T) {
+	huge := []byte{0x81, 0xFF, 0, 0, 1, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+	samples := []struct {
+		name   string
+		frames [][]byte
+		code   int
+	}{
+		{"ping over 125 bytes", [][]byte{frame(0x89, make([]byte, 126))}, websocket.CloseProtocolError},
+		{"fragmented ping", [][]byte{frame(0x09, []byte("a"))}, websocket.CloseProtocolError},
+		{"fragmented close", [][]byte{frame(0x08, []byte{0x03, 0xE8})}, websocket.CloseProtocolError},
+		{"continuation without a message", [][]byte{frame(0x80, []byte("a"))}, websocket.CloseProtocolError},
+		{"message inside a message", [][]byte{frame(0x01, []byte("a")), frame(0x81, []byte("b"))}, websocket.CloseProtocolError},
+		{"reserved bit", [][]byte{frame(0xC1, []byte("a"))}, websocket.CloseProtocolError},
+		{"frame over the limit", [][]byte{huge}, websocket.CloseTooBig},
+		{"fragments over the limit", [][]byte{
+			frame(0x01, make([]byte, websocket.MaxMessageSize/2+1)),
+			frame(0x80, make([]byte, websocket.MaxMessageSize/2+1)),
+		}, websocket.CloseTooBig},
+	}
+	for _, v := range samples {
+		server := echo(t)
+		c := dial(t, server)
+		c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for _, f := range v.frames {
+			c.conn.Write(f)
+		}
+		opcode, payload := c.receive(t)
+		assert.Equal(t, opcode, byte(0x8))
+		if len(payload) != 2 {
+			t.Fatalf("%s: close frame of %d bytes", v.name, len(payload))
+		}
+		if code := int(binary.BigEndian.Uint16(payload)); code != v.code {
+			t.Errorf("%s: closed with %d, expected %d", v.name, code, v.code)
+		}
+		c.conn.Close()
+		server.Close()
+	}
+}
+
+// closeCode reads frames until the close frame and returns its code,
+// without answering pings.
+func (c *client) closeCode(t *testing.T) int {
+	for {
+		opcode, payload := c.receive(t)
+		if opcode == 0x8 {
+			return int(binary.BigEndian.Uint16(payload))
+		}
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	samples := []struct {
+		name  string
+		frame []byte
+	}{
+		{"silent client", nil},
+		// A frame that never arrives whole.
+		{"partial frame", []byte{0x81, 0x85, 1, 2}},
+	}
+	for _, v := range samples {
+		server := echoFrom(t, websocket.Options{ReadTimeout: 100 * time.Millisecond})
+		c := dial(t, server)
+		c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		c.conn.Write(v.frame)
+		started := time.Now()
+		if code := c.closeCode(t); code != websocket.CloseGoingAway {
+			t.Errorf("%s: closed with %d, expected %d", v.name, code, websocket.CloseGoingAway)
+		}
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Errorf("%s: closed after %v", v.name, elapsed)
+		}
+		c.conn.Close()
+		server.Close()
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	server := echoFrom(t, websocket.Options{ReadTimeout: 100 * time.Millisecond})
+	defer server.Close()
+	c := dial(t, server)
+	defer c.conn.Close()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// A client that answers pings stays connected past the timeout.
+	for pings := 0; pings < 5; pings++ {
+		opcode, payload := c.receive(t)
+		assert.Equal(t, opcode, byte(0x9))
+		c.send(0xA, payload)
+	}
+	c.send(0x1, []byte("still here"))
+	for {
+		opcode, payload := c.receive(t)
+		if opcode == 0x9 {
+			continue
+		}
+		assert.Equal(t, opcode, byte(0x1))
+		assert.Equal(t, string(payload), "STILL HERE")
+		break
+	}
+}